@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -14,13 +15,20 @@ import (
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/commands"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/deadletter"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery/poller"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery/webhook"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/device"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/gateway"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/history"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/inbound"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/journal"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/media"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/outbound"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/security"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/status"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/tailscale"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/transcribe"
 )
@@ -39,8 +47,11 @@ func main() {
 	if err != nil {
 		log.Fatalf("transcription config error: %v", err)
 	}
-	if cfg.Kapso.APIKey == "" || cfg.Kapso.PhoneNumberID == "" {
-		log.Fatal("KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID must be set")
+	numbers := cfg.PhoneNumbers()
+	for _, n := range numbers {
+		if n.APIKey == "" || n.PhoneNumberID == "" {
+			log.Fatal("KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID must be set (or api_key and phone_number_id for every [[numbers]] entry)")
+		}
 	}
 
 	mode := cfg.Delivery.Mode
@@ -59,27 +70,136 @@ func main() {
 	}
 	log.Printf("device: id=%s", ident.DeviceID()[:16])
 
-	// Connect to the AI gateway (OpenClaw, ZeroClaw, etc.).
-	gw, err := gateway.New(cfg.Gateway, gateway.WithSigner(ident))
-	if err != nil {
-		log.Fatalf("invalid gateway config: %v", err)
-	}
-	if err := gw.Connect(ctx); err != nil {
-		log.Fatalf("failed to connect to gateway: %v", err)
-	}
-	defer func() { _ = gw.Close() }()
-
+	// Connect to the AI gateway (OpenClaw, ZeroClaw, etc.). Numbers that
+	// point at the same gateway URL share one connection.
 	gwType := cfg.Gateway.Type
 	if gwType == "" {
 		gwType = "openclaw"
 	}
-	log.Printf("gateway: type=%s url=%s", gwType, cfg.Gateway.URL)
-
-	client := kapso.NewClient(cfg.Kapso.APIKey, cfg.Kapso.PhoneNumberID)
+	gateways := make(map[string]gateway.Gateway)
+	for _, n := range numbers {
+		if _, ok := gateways[n.GatewayURL]; ok {
+			continue
+		}
+		gwCfg := cfg.Gateway
+		gwCfg.URL = n.GatewayURL
+		gw, err := gateway.New(gwCfg, gateway.WithSigner(ident), gateway.WithStateDir(cfg.State.Dir))
+		if err != nil {
+			log.Fatalf("invalid gateway config: %v", err)
+		}
+		if err := gw.Connect(ctx); err != nil {
+			log.Fatalf("failed to connect to gateway %s: %v", n.GatewayURL, err)
+		}
+		defer func() { _ = gw.Close() }()
+		gateways[n.GatewayURL] = gw
+		log.Printf("gateway: type=%s url=%s", gwType, n.GatewayURL)
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// Central outbound dispatcher: every message the bridge sends is paced here.
+	queue := &outbound.Queue{
+		Rate:     cfg.Outbound.MessagesPerSecond,
+		Interval: time.Duration(cfg.Outbound.RecipientIntervalMS) * time.Millisecond,
+	}
+	log.Printf("outbound: %.1f msg/s, %dms per recipient",
+		cfg.Outbound.MessagesPerSecond, cfg.Outbound.RecipientIntervalMS)
+	go logQueueDepth(ctx, queue)
+
+	// Command dispatcher (no-op when no commands are configured).
+	dispatcher := commands.New(cfg.Commands)
+	if cfg.Commands.Prefix != "" && len(cfg.Commands.Definitions) > 0 {
+		log.Printf("commands: prefix=%q, %d command(s) configured", cfg.Commands.Prefix, len(cfg.Commands.Definitions))
+	}
+
+	// Inbound journal: accepted messages survive a crash or restart.
+	var jrnl *journal.Journal
+	if j, err := journal.Open(filepath.Join(cfg.State.Dir, "journal")); err != nil {
+		log.Printf("WARN: inbound journal unavailable, in-flight messages are lost on restart: %v", err)
+	} else {
+		jrnl = j
+		defer func() { _ = j.Close() }()
+	}
+
+	// State shared by every number: the outbound queue, conversation history,
+	// inbound scheduling and the persistent stores.
+	hist := history.New(history.DefaultCapacity)
+	statuses := &status.Store{Path: filepath.Join(cfg.State.Dir, "statuses.jsonl")}
+	pool := &inbound.Pool{
+		Concurrency: cfg.Inbound.MaxConcurrent,
+		QueueLimit:  cfg.Inbound.QueueLimit,
+		MaxQueued:   cfg.Inbound.MaxQueued,
+	}
+	deadLetters := &deadletter.Store{Dir: filepath.Join(cfg.State.Dir, "deadletter")}
+	log.Printf("inbound: %d concurrent, %d queued per sender, %d queued in total", cfg.Inbound.MaxConcurrent, cfg.Inbound.QueueLimit, cfg.Inbound.MaxQueued)
+
+	// One bridge per business number, each with its own Kapso client,
+	// gateway session and security roles.
+	bridges := make(map[string]*bridge, len(numbers))
+	extractors := make(map[string]*delivery.Extractor, len(numbers))
+	for i, n := range numbers {
+		client := kapso.NewClient(n.APIKey, n.PhoneNumberID)
+		nb := &bridge{
+			name:         n.Name,
+			gw:           gateways[n.GatewayURL],
+			client:       client,
+			guard:        security.New(n.Security(cfg.Security)),
+			sessionKey:   n.SessionKey,
+			outbound:     queue,
+			dispatcher:   dispatcher,
+			history:      hist,
+			statuses:     statuses,
+			errorMessage: cfg.Gateway.ErrorMessage,
+			quoteReplies: cfg.Delivery.QuoteReplies,
+			onFailure:    cfg.Delivery.OnFailure,
+			alertTo:      cfg.Delivery.AlertTo,
+			journal:      jrnl,
+			inbound:      pool,
+			busyMessage:  cfg.Inbound.BusyMessage,
+			deadLetters:  deadLetters,
+		}
+		bridges[n.PhoneNumberID] = nb
+
+		// Inbound message extraction, shared by all sources.
+		extractor := &delivery.Extractor{
+			Client:           client,
+			Transcriber:      transcriber,
+			MaxAudioSize:     cfg.Transcribe.MaxAudioSize,
+			ForwardReactions: cfg.Delivery.Reactions == "forward",
+			Outbound:         queue,
+		}
+
+		// Local attachment store, so the agent gets files it can actually open.
+		if cfg.Media.Enabled {
+			store := &media.Store{
+				Dir:       filepath.Join(cfg.State.Dir, "media"),
+				Client:    client,
+				MaxSize:   cfg.Media.MaxSize,
+				Retention: time.Duration(cfg.Media.RetentionHours) * time.Hour,
+			}
+			extractor.Media = store
+			if i == 0 {
+				go store.StartCleanup(ctx, time.Hour)
+				log.Printf("media: storing attachments in %s (retention %dh)", store.Dir, cfg.Media.RetentionHours)
+			}
+		}
+		extractors[n.PhoneNumberID] = extractor
+
+		log.Printf("number %s: phone_number_id=%s gateway=%s session=%s",
+			n.Name, n.PhoneNumberID, n.GatewayURL, n.SessionKey)
+	}
+
+	// Events from a number the bridge does not know (or from sources that do
+	// not report one) are handled by the first number.
+	b := bridges[numbers[0].PhoneNumberID]
+	route := func(phoneNumberID string) *bridge {
+		if nb, ok := bridges[phoneNumberID]; ok {
+			return nb
+		}
+		return b
+	}
+
 	// Build source(s) based on mode.
 	var sources []delivery.Source
 	var funnelProc *os.Process
@@ -87,27 +207,56 @@ func main() {
 	runPolling := mode == "polling" || cfg.Delivery.PollFallback
 
 	if runPolling {
-		sources = append(sources, &poller.Poller{
-			Client:       client,
-			Interval:     time.Duration(cfg.Delivery.PollInterval) * time.Second,
-			StateDir:     cfg.State.Dir,
-			StateFile:    filepath.Join(cfg.State.Dir, "last-poll"),
-			Transcriber:  transcriber,
-			MaxAudioSize: cfg.Transcribe.MaxAudioSize,
-		})
-		log.Printf("polling every %ds, gateway=%s session=%s",
-			cfg.Delivery.PollInterval, cfg.Gateway.URL, cfg.Gateway.SessionKey)
+		for i, n := range numbers {
+			// The first number keeps the original state file, so adding
+			// numbers does not reset its cursor.
+			stateFile := filepath.Join(cfg.State.Dir, "last-poll")
+			if i > 0 {
+				stateFile += "-" + n.PhoneNumberID
+			}
+			sources = append(sources, &poller.Poller{
+				Client:    bridges[n.PhoneNumberID].client,
+				Interval:  time.Duration(cfg.Delivery.PollInterval) * time.Second,
+				StateDir:  cfg.State.Dir,
+				StateFile: stateFile,
+				Extractor: extractors[n.PhoneNumberID],
+			})
+		}
+		log.Printf("polling every %ds for %d number(s)", cfg.Delivery.PollInterval, len(numbers))
 	}
 
 	if mode == "tailscale" || mode == "domain" {
+		var archive *webhook.Archive
+		if cfg.Webhook.Archive {
+			archive = &webhook.Archive{
+				Path:    filepath.Join(cfg.State.Dir, "webhooks.jsonl"),
+				MaxSize: cfg.Webhook.ArchiveMaxSize,
+			}
+			log.Printf("webhook: archiving payloads to %s", archive.Path)
+		}
 		sources = append(sources, &webhook.Server{
-			Addr:         cfg.Webhook.Addr,
-			VerifyToken:  cfg.Webhook.VerifyToken,
-			AppSecret:    cfg.Webhook.Secret,
-			Client:       client,
-			Transcriber:  transcriber,
-			MaxAudioSize: cfg.Transcribe.MaxAudioSize,
+			Addr:        cfg.Webhook.Addr,
+			VerifyToken: cfg.Webhook.VerifyToken,
+			AppSecret:   cfg.Webhook.Secret,
+			Extractor:   extractors[numbers[0].PhoneNumberID],
+			Extractors:  extractors,
+			Workers:     cfg.Webhook.Workers,
+			QueueSize:   cfg.Webhook.QueueSize,
+			Archive:     archive,
+			Health: func() map[string]interface{} {
+				return map[string]interface{}{
+					"outbound_queue_depth": queue.Depth(),
+					"inbound_queue_depth":  pool.Depth(),
+					"inbound_running":      pool.Running(),
+					"phone_numbers":        len(numbers),
+					"gateways":             gatewayStates(gateways),
+				}
+			},
+			OnStatus: func(phoneNumberID string, st kapso.Status) {
+				route(phoneNumberID).handleStatus(ctx, st)
+			},
 		})
+		log.Printf("delivery statuses: recording to %s, on_failure=%s", statuses.Path, cfg.Delivery.OnFailure)
 
 		if mode == "tailscale" {
 			_, port, err := net.SplitHostPort(cfg.Webhook.Addr)
@@ -131,36 +280,100 @@ func main() {
 		log.Fatal("no delivery source configured")
 	}
 
-	// Fan-in + dedup.
-	merge := &delivery.Merge{Sources: sources}
+	// Fan-in + dedup. Seen IDs are persisted so a restart does not let a
+	// webhook redelivery or a poller/webhook overlap through.
+	dedup, err := delivery.NewDedup(filepath.Join(cfg.State.Dir, "dedup"), time.Duration(cfg.Delivery.DedupTTL)*time.Hour)
+	if err != nil {
+		log.Printf("WARN: dedup state unavailable, deduplicating in memory only: %v", err)
+		dedup, _ = delivery.NewDedup("", time.Duration(cfg.Delivery.DedupTTL)*time.Hour)
+	}
+	merge := &delivery.Merge{Sources: sources, Dedup: dedup}
 	events := make(chan delivery.Event, 64)
 
 	go func() { _ = merge.Run(ctx, events) }()
 	go merge.StartCleanup(ctx, 10*time.Minute)
 
-	// Security guard.
-	guard := security.New(cfg.Security)
+	// Security guards are per number; mode and limits are shared.
 	log.Printf("security: mode=%s, session_isolation=%v, rate_limit=%d/%ds",
 		cfg.Security.Mode, cfg.Security.SessionIsolation,
 		cfg.Security.RateLimit, cfg.Security.RateWindow)
 
-	// Command dispatcher (no-op when no commands are configured).
-	dispatcher := commands.New(cfg.Commands)
-	if cfg.Commands.Prefix != "" && len(cfg.Commands.Definitions) > 0 {
-		log.Printf("commands: prefix=%q, %d command(s) configured", cfg.Commands.Prefix, len(cfg.Commands.Definitions))
+	// Bursts of messages from one sender become a single agent turn.
+	window := time.Duration(cfg.Inbound.DebounceMS) * time.Millisecond
+	coalescer := &inbound.Coalescer{
+		Window:  window,
+		MaxWait: 4 * window, // a steady stream is still answered
+		Flush: func(_ string, events []delivery.Event) {
+			evt := b.mergeBurst(events)
+			nb := route(evt.PhoneNumberID)
+			if len(events) > 1 {
+				log.Printf("inbound: coalesced %d messages from %s into %s", len(events), evt.From, evt.ID)
+			}
+			sessionKey, role := nb.guard.SessionKey(nb.sessionKey, evt.From), nb.guard.Role(evt.From)
+			if !nb.submit(ctx, evt, func() { nb.relay(ctx, evt, sessionKey, role) }) {
+				nb.complete(evt)
+			}
+		},
 	}
+	if window > 0 {
+		log.Printf("inbound: coalescing bursts within %s", window)
+	}
+
+	// Retry dead letters the operator marked with `kapso-whatsapp-cli dlq retry`.
+	go b.watchDeadLetters(ctx, 10*time.Second, func(evt delivery.Event) {
+		nb := route(evt.PhoneNumberID)
+		nb.accept(evt)
+		sessionKey, role := nb.guard.SessionKey(nb.sessionKey, evt.From), nb.guard.Role(evt.From)
+		nb.submit(ctx, evt, func() { nb.relay(ctx, evt, sessionKey, role) })
+	})
 
-	// Consume loop — identical for all sources.
+	// Replay messages that were accepted but not answered before the last
+	// shutdown. The event ID is reused as the idempotency key, so the gateway
+	// can recognise a request it already saw.
+	if b.journal != nil {
+		for _, e := range b.journal.Pending() {
+			evt := e.Event
+			if e.Attempts >= maxReplayAttempts {
+				log.Printf("journal: giving up on message %s from %s after %d attempts", evt.ID, evt.From, e.Attempts)
+				_ = b.journal.Done(evt.ID)
+				continue
+			}
+			log.Printf("journal: replaying message %s from %s (attempt %d)", evt.ID, evt.From, e.Attempts+1)
+			nb := route(evt.PhoneNumberID)
+			nb.accept(evt)
+			if dispatcher.IsCommand(evt.Text) {
+				sessionKey, role := nb.guard.SessionKey(nb.sessionKey, evt.From), nb.guard.Role(evt.From)
+				if !nb.submit(ctx, evt, func() {
+					nb.handleCommand(ctx, evt, sessionKey, role)
+					nb.complete(evt)
+				}) {
+					nb.complete(evt)
+				}
+				continue
+			}
+			coalescer.Add(evt)
+		}
+	}
+
+	// Consume loop — identical for all sources; each event is handled by the
+	// bridge of the number that received it. It runs until Merge closes
+	// events, so what the sources emit while shutting down is still journaled.
+	consumed := make(chan struct{})
 	go func() {
+		defer close(consumed)
 		for evt := range events {
+			nb := route(evt.PhoneNumberID)
+			guard := nb.guard
 			verdict := guard.Check(evt.From)
 			switch verdict {
 			case security.Deny:
 				log.Printf("guard: blocked unauthorized sender %s", evt.From)
 				if msg := guard.DenyMessage(); msg != "" {
-					if _, err := client.SendText(evt.From, msg); err != nil {
-						log.Printf("guard: failed to send deny message to %s: %v", evt.From, err)
-					}
+					go func(to string) {
+						if err := nb.sendText(ctx, to, msg, ""); err != nil {
+							log.Printf("guard: failed to send deny message to %s: %v", to, err)
+						}
+					}(evt.From)
 				}
 				continue
 			case security.RateLimited:
@@ -169,16 +382,40 @@ func main() {
 			}
 
 			role := guard.Role(evt.From)
-			sessionKey := guard.SessionKey(cfg.Gateway.SessionKey, evt.From)
+			sessionKey := guard.SessionKey(nb.sessionKey, evt.From)
 
-			// Bridge commands are intercepted before the gateway.
+			// Remember the message so later quoted replies can be resolved.
+			nb.history.Record(history.Entry{ID: evt.ID, Peer: evt.From, Text: evt.Text})
+
+			// Dedup has already marked the message as seen, so it is not
+			// fetched again: while shutting down, journal it for the next
+			// start instead of processing it.
+			if ctx.Err() != nil {
+				log.Printf("journal: keeping message %s from %s for the next start", evt.ID, evt.From)
+				nb.accept(evt)
+				continue
+			}
+
+			// Bridge commands are intercepted before the gateway. Messages
+			// held for coalescing go first, to keep the sender's order.
 			if dispatcher.IsCommand(evt.Text) {
-				go handleCommand(ctx, dispatcher, gw, client, evt, sessionKey, role)
+				coalescer.FlushSender(inbound.Key(evt))
+				nb.submit(ctx, evt, func() { nb.handleCommand(ctx, evt, sessionKey, role) })
 				continue
 			}
 
-			// Forward to gateway and wait for agent reply in a goroutine.
-			go handleMessage(ctx, gw, client, evt, sessionKey, role, cfg.Gateway.ErrorMessage)
+			// Journal the message, then hold it for coalescing before it is
+			// queued behind the sender's earlier messages. The typing
+			// indicator shows while the window is open.
+			nb.accept(evt)
+			if window > 0 {
+				go func(id string) {
+					if err := nb.client.MarkReadWithTypingContext(ctx, id); err != nil {
+						log.Printf("inbound: failed to show typing for %s: %v", id, err)
+					}
+				}(evt.ID)
+			}
+			coalescer.Add(evt)
 		}
 	}()
 
@@ -186,44 +423,251 @@ func main() {
 	sig := <-stop
 	log.Printf("received %s, shutting down", sig)
 	cancel()
+	select {
+	case <-consumed:
+	case <-time.After(shutdownTimeout):
+		log.Printf("WARN: delivery sources did not stop within %s; messages still in flight are lost", shutdownTimeout)
+	}
 	cleanupFunnel(funnelProc)
 }
 
+// shutdownTimeout bounds how long shutdown waits for the delivery sources to
+// hand over the messages they already accepted.
+const shutdownTimeout = time.Minute
+
+// bridge holds what the relay handlers share across messages. There is one
+// bridge per business number; the outbound queue, history, journal, inbound
+// pool and stores are shared between them.
+type bridge struct {
+	name         string // number label for logs
+	gw           gateway.Gateway
+	client       *kapso.Client // sends from this number
+	guard        *security.Guard
+	sessionKey   string // base gateway session key
+	outbound     *outbound.Queue
+	dispatcher   *commands.Dispatcher
+	history      *history.Store
+	statuses     *status.Store
+	errorMessage string
+	quoteReplies bool   // send the first reply chunk as a quoted reply
+	onFailure    string // "log", "resend" or "alert"
+	alertTo      string
+
+	journal     *journal.Journal // nil = no crash recovery
+	inbound     *inbound.Pool    // per-sender FIFO processing
+	busyMessage string
+	deadLetters *deadletter.Store
+}
+
+// maxReplayAttempts bounds how often a journaled message is processed, so a
+// message that crashes the bridge cannot do so forever.
+const maxReplayAttempts = 3
+
+// accept writes evt to the journal before it is processed.
+func (b *bridge) accept(evt delivery.Event) {
+	if b.journal == nil {
+		return
+	}
+	if _, err := b.journal.Add(evt); err != nil {
+		log.Printf("journal: failed to record %s: %v", evt.ID, err)
+	}
+}
+
+// submit queues job in evt's sender lane. When the sender already has too
+// many messages waiting it sends the busy message instead and returns false.
+func (b *bridge) submit(ctx context.Context, evt delivery.Event, job func()) bool {
+	err := b.inbound.Submit(inbound.Key(evt), job)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, inbound.ErrQueueFull) {
+		log.Printf("inbound: queue full for %s, dropping message %s", evt.From, evt.ID)
+	} else {
+		log.Printf("inbound: failed to queue message %s: %v", evt.ID, err)
+	}
+	if b.busyMessage != "" {
+		go func() {
+			if err := b.sendText(ctx, evt.From, b.busyMessage, ""); err != nil {
+				log.Printf("inbound: failed to send busy message to %s: %v", evt.From, err)
+			}
+		}()
+	}
+	return false
+}
+
+// relay runs handleMessage and marks the message done in the journal. A
+// message the agent failed to answer is kept as a dead letter; one that
+// succeeds clears any earlier dead letter. When the bridge is shutting down
+// the journal entry is left pending for replay instead.
+func (b *bridge) relay(ctx context.Context, evt delivery.Event, sessionKey, role string) {
+	err := b.handleMessage(ctx, evt, sessionKey, role)
+	if ctx.Err() != nil {
+		return
+	}
+	var rerr *relayError
+	switch {
+	case errors.As(err, &rerr):
+		if dlErr := b.deadLetters.Add(evt, rerr.kind, rerr.err); dlErr != nil {
+			log.Printf("deadletter: failed to store %s: %v", evt.ID, dlErr)
+		} else {
+			log.Printf("deadletter: stored %s (%s)", evt.ID, rerr.kind)
+		}
+	case err == nil:
+		if dlErr := b.deadLetters.Drop(evt.ID); dlErr != nil && !errors.Is(dlErr, deadletter.ErrNotFound) {
+			log.Printf("deadletter: failed to drop %s: %v", evt.ID, dlErr)
+		}
+	}
+	b.complete(evt)
+}
+
+// relayError reports why the agent's answer to a message was lost; kind is
+// one of the deadletter.Kind* values.
+type relayError struct {
+	kind string
+	err  error
+}
+
+func (e *relayError) Error() string { return e.kind + ": " + e.err.Error() }
+func (e *relayError) Unwrap() error { return e.err }
+
+// watchDeadLetters hands dead letters marked for retry to retry every interval
+// until ctx is cancelled.
+func (b *bridge) watchDeadLetters(ctx context.Context, interval time.Duration, retry func(delivery.Event)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			entries, err := b.deadLetters.TakeRetries()
+			if err != nil {
+				log.Printf("deadletter: %v", err)
+			}
+			for _, e := range entries {
+				log.Printf("deadletter: retrying %s from %s (attempt %d)", e.Event.ID, e.Event.From, e.Attempts+1)
+				retry(e.Event)
+			}
+		}
+	}
+}
+
+// complete marks every message evt stands for as done in the journal.
+func (b *bridge) complete(evt delivery.Event) {
+	if b.journal == nil {
+		return
+	}
+	for _, id := range evt.IDs() {
+		if err := b.journal.Done(id); err != nil {
+			log.Printf("journal: failed to complete %s: %v", id, err)
+		}
+	}
+}
+
+// mergeBurst turns consecutive events from one sender into one event, one
+// line per message. Each message keeps its own quoted-reply context. The
+// merged event takes the last message's ID, which is marked read and used as
+// the idempotency key, and the attachments of every message. Its type is
+// "mixed" when the messages differ in type.
+func (b *bridge) mergeBurst(events []delivery.Event) delivery.Event {
+	if len(events) == 1 {
+		return events[0]
+	}
+	last := events[len(events)-1]
+	merged := delivery.Event{
+		ID:            last.ID,
+		From:          last.From,
+		Name:          last.Name,
+		Type:          last.Type,
+		Timestamp:     last.Timestamp,
+		PhoneNumberID: last.PhoneNumberID,
+	}
+	lines := make([]string, 0, len(events))
+	for _, e := range events {
+		text := e.Text
+		if quote := b.history.Quote(e.ReplyTo); quote != "" {
+			text = quote + "\n" + text
+		}
+		lines = append(lines, text)
+		merged.Parts = append(merged.Parts, e.IDs()...)
+		merged.Attachments = append(merged.Attachments, e.Attachments...)
+		if e.Location != nil {
+			merged.Location = e.Location
+		}
+		if e.Type != merged.Type {
+			merged.Type = "mixed"
+		}
+	}
+	merged.Text = strings.Join(lines, "\n")
+	return merged
+}
+
+// sendText sends text to a WhatsApp user through the outbound queue, quoting
+// replyTo when set.
+func (b *bridge) sendText(ctx context.Context, to, text, replyTo string) error {
+	return b.sendChunks(ctx, to, []string{text}, replyTo)
+}
+
+// sendChunks sends the chunks of one reply back to back through the outbound
+// queue. Only the first chunk quotes replyTo. Sent messages are recorded so
+// the user can quote them back later.
+func (b *bridge) sendChunks(ctx context.Context, to string, chunks []string, replyTo string) error {
+	sends := make([]outbound.SendFunc, len(chunks))
+	for i, chunk := range chunks {
+		chunk, quote := chunk, ""
+		if i == 0 {
+			quote = replyTo
+		}
+		sends[i] = func(ctx context.Context) error {
+			resp, err := b.client.SendTextReplyContext(ctx, to, chunk, quote)
+			if err != nil {
+				return err
+			}
+			if len(resp.Messages) > 0 {
+				b.history.Record(history.Entry{ID: resp.Messages[0].ID, Peer: to, Text: chunk, Outbound: true})
+			}
+			return nil
+		}
+	}
+	return b.outbound.Send(ctx, to, sends...)
+}
+
 // handleCommand dispatches a bridge-level command and sends the reply to WhatsApp.
 // Commands are executed without involving the AI gateway (except agent-type commands).
-func handleCommand(ctx context.Context, d *commands.Dispatcher, gw gateway.Gateway, client *kapso.Client, evt delivery.Event, sessionKey, role string) {
+func (b *bridge) handleCommand(ctx context.Context, evt delivery.Event, sessionKey, role string) {
+	d, client := b.dispatcher, b.client
 	from := evt.From
 	if !strings.HasPrefix(from, "+") {
 		from = "+" + from
 	}
 
-	if err := client.MarkReadWithTyping(evt.ID); err != nil {
+	if err := client.MarkReadWithTypingContext(ctx, evt.ID); err != nil {
 		log.Printf("command: failed to mark read for %s: %v", evt.ID, err)
 	}
 
 	name, args, ok := d.Parse(evt.Text)
 	if !ok {
 		msg := fmt.Sprintf("Unknown command. Send %shelp for available commands.", d.Prefix())
-		if _, err := client.SendText(from, msg); err != nil {
+		if err := b.sendText(ctx, from, msg, ""); err != nil {
 			log.Printf("command: failed to send reply to %s: %v", from, err)
 		}
-		_ = client.MarkRead(evt.ID)
+		_ = client.MarkReadContext(ctx, evt.ID)
 		return
 	}
 	if !d.Exists(name) {
 		msg := fmt.Sprintf("Unknown command %s%s. Send %shelp for available commands.", d.Prefix(), name, d.Prefix())
-		if _, err := client.SendText(from, msg); err != nil {
+		if err := b.sendText(ctx, from, msg, ""); err != nil {
 			log.Printf("command: failed to send reply to %s: %v", from, err)
 		}
-		_ = client.MarkRead(evt.ID)
+		_ = client.MarkReadContext(ctx, evt.ID)
 		return
 	}
 	if !d.CanRun(name, role) {
 		msg := fmt.Sprintf("You don't have permission to use %s%s.", d.Prefix(), name)
-		if _, err := client.SendText(from, msg); err != nil {
+		if err := b.sendText(ctx, from, msg, ""); err != nil {
 			log.Printf("command: failed to send reply to %s: %v", from, err)
 		}
-		_ = client.MarkRead(evt.ID)
+		_ = client.MarkReadContext(ctx, evt.ID)
 		return
 	}
 
@@ -231,43 +675,47 @@ func handleCommand(ctx context.Context, d *commands.Dispatcher, gw gateway.Gatew
 
 	// Send ack before potentially slow or self-terminating commands.
 	if ack := d.Ack(name); ack != "" {
-		if _, err := client.SendText(from, ack); err != nil {
+		if err := b.sendText(ctx, from, ack, ""); err != nil {
 			log.Printf("command: failed to send ack to %s: %v", from, err)
 		}
 	}
 
-	req := &gateway.Request{
-		SessionKey:     sessionKey,
-		IdempotencyKey: evt.ID,
-		From:           evt.From,
-		FromName:       evt.Name,
-		Role:           role,
-	}
-	reply := d.Handle(ctx, name, args, role, sessionKey, gw, req, client)
+	req := gateway.NewRequest(evt, sessionKey, role, "")
+	reply := d.Handle(ctx, name, args, role, sessionKey, b.gw, req, client)
 	if reply != "" {
 		chunks := gateway.SplitMessage(gateway.MdToWhatsApp(reply), 4096)
-		for _, chunk := range chunks {
-			if _, err := client.SendText(from, chunk); err != nil {
-				log.Printf("command: failed to send reply chunk to %s: %v", from, err)
-			}
+		if err := b.sendChunks(ctx, from, chunks, ""); err != nil {
+			log.Printf("command: failed to send reply to %s: %v", from, err)
 		}
 	}
 
-	if err := client.MarkRead(evt.ID); err != nil {
+	if err := client.MarkReadContext(ctx, evt.ID); err != nil {
 		log.Printf("command: failed to dismiss typing for %s: %v", evt.ID, err)
 	}
+
+	// Mark the command message as done.
+	react := func(ctx context.Context) error {
+		_, err := client.SendReactionContext(ctx, from, evt.ID, "✅")
+		return err
+	}
+	if err := b.outbound.Send(ctx, from, react); err != nil {
+		log.Printf("command: failed to react to %s: %v", evt.ID, err)
+	}
 }
 
 // handleMessage sends a message to the gateway, waits for the agent's reply,
-// and sends it back to the WhatsApp sender.
-func handleMessage(ctx context.Context, gw gateway.Gateway, client *kapso.Client, evt delivery.Event, sessionKey, role, errorMessage string) {
+// and sends it back to the WhatsApp sender. When the user quoted an earlier
+// message, the quoted text is prepended so the agent knows what they refer to.
+// It returns a *relayError when no reply reached the user.
+func (b *bridge) handleMessage(ctx context.Context, evt delivery.Event, sessionKey, role string) error {
+	client := b.client
 	from := evt.From
 	if !strings.HasPrefix(from, "+") {
 		from = "+" + from
 	}
 
 	// Show typing indicator.
-	if err := client.MarkReadWithTyping(evt.ID); err != nil {
+	if err := client.MarkReadWithTypingContext(ctx, evt.ID); err != nil {
 		log.Printf("relay: failed to mark read with typing for %s: %v", evt.ID, err)
 	}
 
@@ -282,56 +730,145 @@ func handleMessage(ctx context.Context, gw gateway.Gateway, client *kapso.Client
 			case <-typingCtx.Done():
 				return
 			case <-ticker.C:
-				if err := client.MarkReadWithTyping(evt.ID); err != nil {
+				if err := client.MarkReadWithTypingContext(typingCtx, evt.ID); err != nil {
 					log.Printf("relay: failed to refresh typing for %s: %v", evt.ID, err)
 				}
 			}
 		}
 	}()
 
-	log.Printf("forwarded message %s from %s [role: %s, session: %s]", evt.ID, evt.From, role, sessionKey)
+	log.Printf("forwarded message %s from %s [number: %s, role: %s, session: %s]", evt.ID, evt.From, b.name, role, sessionKey)
+
+	text := evt.Text
+	if quote := b.history.Quote(evt.ReplyTo); quote != "" {
+		text = quote + "\n" + text
+	}
 
 	msgCtx, msgCancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer msgCancel()
 
-	reply, err := gw.SendAndReceive(msgCtx, &gateway.Request{
-		SessionKey:     sessionKey,
-		IdempotencyKey: evt.ID,
-		From:           evt.From,
-		FromName:       evt.Name,
-		Role:           role,
-		Text:           evt.Text,
-	})
+	reply, err := b.gw.SendAndReceive(msgCtx, gateway.NewRequest(evt, sessionKey, role, text))
 
 	typingCancel()
 
 	if err != nil {
 		log.Printf("error getting agent reply for %s: %v", evt.ID, err)
-		if errorMessage != "" {
-			if _, sendErr := client.SendText(from, errorMessage); sendErr != nil {
+		if b.errorMessage != "" {
+			if sendErr := b.sendText(ctx, from, b.errorMessage, ""); sendErr != nil {
 				log.Printf("relay: failed to send error message to %s: %v", from, sendErr)
 			}
 		}
-		if markErr := client.MarkRead(evt.ID); markErr != nil {
+		if markErr := client.MarkReadContext(ctx, evt.ID); markErr != nil {
 			log.Printf("relay: failed to dismiss typing for %s: %v", evt.ID, markErr)
 		}
-		return
+		kind := deadletter.KindGateway
+		if errors.Is(err, context.DeadlineExceeded) {
+			kind = deadletter.KindTimeout
+		}
+		return &relayError{kind: kind, err: err}
 	}
 
-	// Format and send reply.
-	text := gateway.MdToWhatsApp(reply)
-	chunks := gateway.SplitMessage(text, 4096)
-	for _, chunk := range chunks {
-		if _, err := client.SendText(from, chunk); err != nil {
-			log.Printf("relay: failed to send WhatsApp chunk to %s: %v", from, err)
-		}
+	// Format and send reply. Only the first chunk quotes the user's message.
+	chunks := gateway.SplitMessage(gateway.MdToWhatsApp(reply), 4096)
+	replyTo := ""
+	if b.quoteReplies {
+		replyTo = evt.ID
+	}
+	sendErr := b.sendChunks(ctx, from, chunks, replyTo)
+	if sendErr != nil {
+		log.Printf("relay: failed to send WhatsApp reply to %s: %v", from, sendErr)
+	} else {
+		log.Printf("relay: sent %d chunk(s) to %s", len(chunks), from)
 	}
-	log.Printf("relay: sent %d chunk(s) to %s", len(chunks), from)
 
 	// Dismiss typing indicator.
-	if err := client.MarkRead(evt.ID); err != nil {
+	if err := client.MarkReadContext(ctx, evt.ID); err != nil {
 		log.Printf("relay: failed to dismiss typing for %s: %v", evt.ID, err)
 	}
+
+	if sendErr != nil {
+		return &relayError{kind: deadletter.KindSend, err: sendErr}
+	}
+	return nil
+}
+
+// handleStatus records a delivery status and, when one of the bridge's own
+// messages failed, re-sends it once or alerts the admin per on_failure.
+func (b *bridge) handleStatus(ctx context.Context, st kapso.Status) {
+	u := status.FromWebhook(st)
+	entry, ours := b.history.Get(u.ID)
+	ours = ours && entry.Outbound
+	if u.Recipient == "" && ours {
+		u.Recipient = entry.Peer
+	}
+	if err := b.statuses.Record(u); err != nil {
+		log.Printf("status: failed to record %s for %s: %v", u.Status, u.ID, err)
+	}
+	if !u.Failed() || !ours {
+		return
+	}
+
+	log.Printf("status: reply %s to %s failed: %d %s", u.ID, entry.Peer, u.ErrorCode, u.ErrorTitle)
+
+	switch b.onFailure {
+	case "resend":
+		if entry.Resend {
+			log.Printf("status: %s was already a re-send, giving up", u.ID)
+			return
+		}
+		resend := func(ctx context.Context) error {
+			resp, err := b.client.SendTextContext(ctx, entry.Peer, entry.Text)
+			if err != nil {
+				return err
+			}
+			if len(resp.Messages) > 0 {
+				id := resp.Messages[0].ID
+				b.history.Record(history.Entry{ID: id, Peer: entry.Peer, Text: entry.Text, Outbound: true, Resend: true})
+			}
+			return nil
+		}
+		if err := b.outbound.Send(ctx, entry.Peer, resend); err != nil {
+			log.Printf("status: failed to re-send %s to %s: %v", u.ID, entry.Peer, err)
+		}
+	case "alert":
+		if entry.Peer == b.alertTo {
+			return // an undeliverable alert must not trigger another one
+		}
+		msg := fmt.Sprintf("⚠️ Reply to %s was not delivered (error %d: %s). Message ID: %s",
+			entry.Peer, u.ErrorCode, u.ErrorTitle, u.ID)
+		if err := b.sendText(ctx, b.alertTo, msg, ""); err != nil {
+			log.Printf("status: failed to alert %s: %v", b.alertTo, err)
+		}
+	}
+}
+
+// gatewayStates reports the connection state of each gateway by URL, for
+// gateways that keep a connection open.
+func gatewayStates(gateways map[string]gateway.Gateway) map[string]string {
+	states := make(map[string]string, len(gateways))
+	for url, gw := range gateways {
+		if sr, ok := gw.(gateway.StateReporter); ok {
+			states[url] = string(sr.State())
+		}
+	}
+	return states
+}
+
+// logQueueDepth periodically logs the outbound queue depth while messages are
+// waiting, so sustained backpressure shows up in the logs.
+func logQueueDepth(ctx context.Context, q *outbound.Queue) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if d := q.Depth(); d > 0 {
+				log.Printf("outbound: %d message(s) queued", d)
+			}
+		}
+	}
 }
 
 // cleanupFunnel gracefully stops the tailscale funnel process if it was started.
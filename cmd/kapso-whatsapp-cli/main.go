@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/deadletter"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery/webhook"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/preflight"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/status"
 )
 
 func main() {
@@ -17,11 +28,40 @@ func main() {
 		os.Exit(1)
 	}
 
+	// --number selects the [[numbers]] entry the send commands use.
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--number" && i+1 < len(args) {
+			number = args[i+1]
+			args = append(args[:i:i], args[i+2:]...)
+			break
+		}
+	}
+	os.Args = append(os.Args[:2:2], args...)
+
 	switch os.Args[1] {
 	case "send":
 		handleSend(os.Args[2:])
+	case "send-buttons":
+		handleSendButtons(os.Args[2:])
+	case "send-list":
+		handleSendList(os.Args[2:])
+	case "send-template":
+		handleSendTemplate(os.Args[2:])
+	case "send-location":
+		handleSendLocation(os.Args[2:])
+	case "send-contact":
+		handleSendContact(os.Args[2:])
+	case "templates":
+		handleTemplates(os.Args[2:])
 	case "status":
 		handleStatus()
+	case "msg-status":
+		handleMsgStatus(os.Args[2:])
+	case "dlq":
+		handleDLQ(os.Args[2:])
+	case "webhook":
+		handleWebhook(os.Args[2:])
 	case "preflight":
 		handlePreflight()
 	case "help", "--help", "-h":
@@ -34,7 +74,7 @@ func main() {
 }
 
 func handleSend(args []string) {
-	var to, text string
+	var to, text, file, caption string
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -48,6 +88,16 @@ func handleSend(args []string) {
 				text = args[i+1]
 				i++
 			}
+		case "--file":
+			if i+1 < len(args) {
+				file = args[i+1]
+				i++
+			}
+		case "--caption":
+			if i+1 < len(args) {
+				caption = args[i+1]
+				i++
+			}
 		default:
 			// Allow positional: send +NUMBER "message"
 			if to == "" && strings.HasPrefix(args[i], "+") {
@@ -58,29 +108,426 @@ func handleSend(args []string) {
 		}
 	}
 
-	if to == "" || text == "" {
+	if to == "" || (text == "" && file == "") {
 		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send --to +NUMBER --text \"message\"")
+		fmt.Fprintln(os.Stderr, "       kapso-whatsapp-cli send --to +NUMBER --file PATH [--caption \"text\"]")
 		os.Exit(1)
 	}
 
-	cfg, err := config.Load()
+	client := newClient()
+
+	var resp *kapso.SendMessageResponse
+	var err error
+	if file != "" {
+		if caption == "" {
+			caption = text
+		}
+		resp, err = sendFile(client, to, file, caption)
+	} else {
+		resp, err = client.SendText(to, text)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	printSent(resp)
+}
+
+func handleSendButtons(args []string) {
+	var to, text string
+	var buttons []kapso.ButtonReply
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 < len(args) {
+				to = args[i+1]
+				i++
+			}
+		case "--text":
+			if i+1 < len(args) {
+				text = args[i+1]
+				i++
+			}
+		case "--button":
+			if i+1 < len(args) {
+				id, title := splitOption(args[i+1])
+				buttons = append(buttons, kapso.ButtonReply{ID: id, Title: title})
+				i++
+			}
+		}
+	}
+
+	if to == "" || text == "" || len(buttons) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send-buttons --to +NUMBER --text \"question\" --button ID=Title [--button ...]")
+		os.Exit(1)
+	}
+
+	resp, err := newClient().SendButtons(to, text, buttons)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+	printSent(resp)
+}
+
+func handleSendList(args []string) {
+	var to, text, buttonText string
+	var sections []kapso.ListSection
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 < len(args) {
+				to = args[i+1]
+				i++
+			}
+		case "--text":
+			if i+1 < len(args) {
+				text = args[i+1]
+				i++
+			}
+		case "--button-text":
+			if i+1 < len(args) {
+				buttonText = args[i+1]
+				i++
+			}
+		case "--section":
+			if i+1 < len(args) {
+				sections = append(sections, kapso.ListSection{Title: args[i+1]})
+				i++
+			}
+		case "--row":
+			if i+1 < len(args) {
+				if len(sections) == 0 {
+					sections = append(sections, kapso.ListSection{})
+				}
+				id, rest := splitOption(args[i+1])
+				title, desc := rest, ""
+				if j := strings.Index(rest, "="); j >= 0 {
+					title, desc = rest[:j], rest[j+1:]
+				}
+				last := &sections[len(sections)-1]
+				last.Rows = append(last.Rows, kapso.ListRow{ID: id, Title: title, Description: desc})
+				i++
+			}
+		}
+	}
 
-	if cfg.Kapso.APIKey == "" || cfg.Kapso.PhoneNumberID == "" {
-		fmt.Fprintln(os.Stderr, "error: KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID must be set")
+	if to == "" || text == "" || len(sections) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send-list --to +NUMBER --text \"question\" --button-text \"Options\" [--section \"Title\"] --row ID=Title[=Description] [--row ...]")
 		os.Exit(1)
 	}
+	if buttonText == "" {
+		buttonText = "Options"
+	}
 
-	client := kapso.NewClient(cfg.Kapso.APIKey, cfg.Kapso.PhoneNumberID)
-	resp, err := client.SendText(to, text)
+	resp, err := newClient().SendList(to, text, buttonText, sections)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+	printSent(resp)
+}
+
+func handleSendTemplate(args []string) {
+	var to, name, lang string
+	var header, body, buttons []kapso.TemplateParameter
 
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 < len(args) {
+				to = args[i+1]
+				i++
+			}
+		case "--name":
+			if i+1 < len(args) {
+				name = args[i+1]
+				i++
+			}
+		case "--lang":
+			if i+1 < len(args) {
+				lang = args[i+1]
+				i++
+			}
+		case "--param":
+			if i+1 < len(args) {
+				body = append(body, kapso.TextParameter(args[i+1]))
+				i++
+			}
+		case "--header-param":
+			if i+1 < len(args) {
+				header = append(header, kapso.TextParameter(args[i+1]))
+				i++
+			}
+		case "--button-payload":
+			if i+1 < len(args) {
+				buttons = append(buttons, kapso.PayloadParameter(args[i+1]))
+				i++
+			}
+		}
+	}
+
+	if to == "" || name == "" {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send-template --to +NUMBER --name TEMPLATE [--lang en_US] [--param VALUE ...] [--header-param VALUE] [--button-payload PAYLOAD ...]")
+		os.Exit(1)
+	}
+	if lang == "" {
+		lang = "en_US"
+	}
+
+	var components []kapso.TemplateComponent
+	if len(header) > 0 {
+		components = append(components, kapso.HeaderComponent(header...))
+	}
+	if len(body) > 0 {
+		components = append(components, kapso.BodyComponent(body...))
+	}
+	for i, p := range buttons {
+		components = append(components, kapso.ButtonComponent("quick_reply", i, p))
+	}
+
+	resp, err := newClient().SendTemplate(to, name, lang, components)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	printSent(resp)
+}
+
+func handleSendLocation(args []string) {
+	var to, lat, lng string
+	var loc kapso.LocationContent
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 < len(args) {
+				to = args[i+1]
+				i++
+			}
+		case "--lat":
+			if i+1 < len(args) {
+				lat = args[i+1]
+				i++
+			}
+		case "--lng":
+			if i+1 < len(args) {
+				lng = args[i+1]
+				i++
+			}
+		case "--name":
+			if i+1 < len(args) {
+				loc.Name = args[i+1]
+				i++
+			}
+		case "--address":
+			if i+1 < len(args) {
+				loc.Address = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if to == "" || lat == "" || lng == "" {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send-location --to +NUMBER --lat LATITUDE --lng LONGITUDE [--name \"Office\"] [--address \"Street 1\"]")
+		os.Exit(1)
+	}
+
+	var err error
+	if loc.Latitude, err = strconv.ParseFloat(lat, 64); err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --lat %q\n", lat)
+		os.Exit(1)
+	}
+	if loc.Longitude, err = strconv.ParseFloat(lng, 64); err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --lng %q\n", lng)
+		os.Exit(1)
+	}
+
+	resp, err := newClient().SendLocation(to, loc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	printSent(resp)
+}
+
+func handleSendContact(args []string) {
+	var to string
+	var card kapso.ContactCard
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--to":
+			if i+1 < len(args) {
+				to = args[i+1]
+				i++
+			}
+		case "--name":
+			if i+1 < len(args) {
+				card.Name.FormattedName = args[i+1]
+				i++
+			}
+		case "--phone":
+			if i+1 < len(args) {
+				card.Phones = append(card.Phones, kapso.ContactPhone{Phone: args[i+1], Type: "CELL"})
+				i++
+			}
+		case "--email":
+			if i+1 < len(args) {
+				card.Emails = append(card.Emails, kapso.ContactEmail{Email: args[i+1]})
+				i++
+			}
+		case "--company":
+			if i+1 < len(args) {
+				card.Org = &kapso.ContactOrg{Company: args[i+1]}
+				i++
+			}
+		}
+	}
+
+	if to == "" || card.Name.FormattedName == "" || (len(card.Phones) == 0 && len(card.Emails) == 0) {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli send-contact --to +NUMBER --name \"Full Name\" --phone +NUMBER [--phone ...] [--email ADDRESS] [--company NAME]")
+		os.Exit(1)
+	}
+
+	// Split the display name so WhatsApp can offer "Add contact" with proper fields.
+	if first, last, ok := strings.Cut(card.Name.FormattedName, " "); ok {
+		card.Name.FirstName, card.Name.LastName = first, last
+	} else {
+		card.Name.FirstName = card.Name.FormattedName
+	}
+
+	resp, err := newClient().SendContacts(to, []kapso.ContactCard{card})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	printSent(resp)
+}
+
+func handleTemplates(args []string) {
+	params := kapso.ListTemplatesParams{Limit: 100}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--name":
+			if i+1 < len(args) {
+				params.Name = args[i+1]
+				i++
+			}
+		case "--status":
+			if i+1 < len(args) {
+				params.Status = strings.ToUpper(args[i+1])
+				i++
+			}
+		}
+	}
+
+	client := newClient()
+	if client.BusinessAccountID == "" {
+		fmt.Fprintln(os.Stderr, "error: KAPSO_BUSINESS_ACCOUNT_ID (or kapso.business_account_id) must be set to list templates")
+		os.Exit(1)
+	}
+
+	for {
+		resp, err := client.ListTemplates(params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, t := range resp.Data {
+			fmt.Printf("%s [%s] %s %s\n", t.Name, t.Language, t.Status, strings.ToLower(t.Category))
+			if body := t.Body(); body != "" {
+				fmt.Printf("    %s\n", strings.ReplaceAll(body, "\n", "\n    "))
+			}
+		}
+		if resp.Paging == nil || resp.Paging.Cursors.After == "" || len(resp.Data) == 0 {
+			return
+		}
+		params.After = resp.Paging.Cursors.After
+	}
+}
+
+// splitOption splits "ID=Title" at the first "=". Without "=", the value is
+// used as both ID and title.
+func splitOption(v string) (id, title string) {
+	if i := strings.Index(v, "="); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, v
+}
+
+// sendFile uploads a local file (or references a public HTTPS URL) and sends
+// it as the WhatsApp message type matching its MIME type.
+func sendFile(client *kapso.Client, to, path, caption string) (*kapso.SendMessageResponse, error) {
+	if strings.HasPrefix(path, "https://") {
+		mimeType := mime.TypeByExtension(filepath.Ext(path))
+		media := kapso.MediaObject{Link: path, Caption: caption, Filename: filepath.Base(path)}
+		return client.SendMedia(to, kapso.MediaKind(mimeType), media)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	// Strip parameters such as "; charset=utf-8" — the upload API rejects them.
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+
+	id, err := client.UploadMedia(filepath.Base(path), mimeType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	media := kapso.MediaObject{ID: id, Caption: caption, Filename: filepath.Base(path)}
+	return client.SendMedia(to, kapso.MediaKind(mimeType), media)
+}
+
+// number is the --number flag: the name or phone number ID of the
+// [[numbers]] entry to send from. Empty means the first number.
+var number string
+
+// newClient loads config and builds a Kapso client for the selected number,
+// exiting on missing credentials.
+func newClient() *kapso.Client {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var n config.NumberConfig
+	for _, c := range cfg.PhoneNumbers() {
+		if c.PhoneNumberID != "" && (number == "" || c.Name == number || c.PhoneNumberID == number) {
+			n = c
+			break
+		}
+	}
+	if number != "" && n.PhoneNumberID == "" {
+		fmt.Fprintf(os.Stderr, "error: no configured number named %q\n", number)
+		os.Exit(1)
+	}
+
+	if n.APIKey == "" || n.PhoneNumberID == "" {
+		fmt.Fprintln(os.Stderr, "error: KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID (or a [[numbers]] entry) must be set")
+		os.Exit(1)
+	}
+
+	client := kapso.NewClient(n.APIKey, n.PhoneNumberID)
+	client.BusinessAccountID = cfg.Kapso.BusinessAccountID
+	return client
+}
+
+// printSent reports the ID of a successfully sent message.
+func printSent(resp *kapso.SendMessageResponse) {
 	if len(resp.Messages) > 0 {
 		fmt.Printf("sent (id: %s)\n", resp.Messages[0].ID)
 	} else {
@@ -107,12 +554,327 @@ func handleStatus() {
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode == http.StatusOK {
-		fmt.Println("webhook server: ok")
-	} else {
+	if resp.StatusCode != http.StatusOK {
 		fmt.Fprintf(os.Stderr, "webhook server: unhealthy (status %d)\n", resp.StatusCode)
 		os.Exit(1)
 	}
+	fmt.Println("webhook server: ok")
+
+	// Newer bridges report metrics as JSON; older ones answer plain "ok".
+	var info map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return
+	}
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		if k != "status" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// Nested metrics (e.g. gateway states by URL) get one line each.
+		if m, ok := info[k].(map[string]interface{}); ok {
+			fmt.Printf("  %s:\n", k)
+			sub := make([]string, 0, len(m))
+			for sk := range m {
+				sub = append(sub, sk)
+			}
+			sort.Strings(sub)
+			for _, sk := range sub {
+				fmt.Printf("    %s: %v\n", sk, m[sk])
+			}
+			continue
+		}
+		fmt.Printf("  %s: %v\n", k, info[k])
+	}
+}
+
+// handleMsgStatus prints the delivery history of one outbound message, as
+// recorded by the bridge from webhook status events.
+func handleMsgStatus(args []string) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "usage: kapso-whatsapp-cli msg-status MESSAGE_ID")
+		os.Exit(1)
+	}
+	id := args[0]
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	updates, err := status.Lookup(filepath.Join(cfg.State.Dir, "statuses.jsonl"), id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(updates) == 0 {
+		fmt.Printf("no status recorded for %s (statuses need webhook delivery)\n", id)
+		os.Exit(1)
+	}
+
+	for _, u := range updates {
+		line := fmt.Sprintf("%s  %-9s", u.Time.Local().Format("2006-01-02 15:04:05"), u.Status)
+		if u.Recipient != "" {
+			line += "  to " + u.Recipient
+		}
+		if u.Failed() {
+			line += fmt.Sprintf("  error %d: %s", u.ErrorCode, u.ErrorTitle)
+			if u.ErrorDetail != "" {
+				line += " — " + u.ErrorDetail
+			}
+		}
+		fmt.Println(line)
+	}
+}
+
+// handleDLQ manages messages the agent failed to answer. Retries are picked
+// up by the running bridge, which re-sends them to the agent.
+func handleDLQ(args []string) {
+	usage := "usage: kapso-whatsapp-cli dlq list | show ID | retry ID|--all | drop ID|--all"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	store := &deadletter.Store{Dir: filepath.Join(cfg.State.Dir, "deadletter")}
+
+	entries, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(entries) == 0 {
+			fmt.Println("no dead letters")
+			return
+		}
+		for _, e := range entries {
+			mark := ""
+			if e.Retry {
+				mark = "  (retry pending)"
+			}
+			fmt.Printf("%s  %s  %-7s  x%d  %s%s\n", e.LastFailed.Local().Format("2006-01-02 15:04"),
+				e.Event.ID, e.Kind, e.Attempts, e.Event.From, mark)
+		}
+		return
+	case "show":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(1)
+		}
+		e, err := store.Get(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("id:           %s\n", e.Event.ID)
+		fmt.Printf("from:         %s %s\n", e.Event.From, e.Event.Name)
+		fmt.Printf("kind:         %s\n", e.Kind)
+		fmt.Printf("error:        %s\n", e.Error)
+		fmt.Printf("attempts:     %d\n", e.Attempts)
+		fmt.Printf("first failed: %s\n", e.FirstFailed.Local().Format(time.RFC3339))
+		fmt.Printf("last failed:  %s\n", e.LastFailed.Local().Format(time.RFC3339))
+		fmt.Printf("text:\n%s\n", e.Event.Text)
+		return
+	case "retry", "drop":
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	ids := []string{args[1]}
+	if args[1] == "--all" {
+		ids = ids[:0]
+		for _, e := range entries {
+			ids = append(ids, e.Event.ID)
+		}
+	}
+
+	failed := false
+	for _, id := range ids {
+		var err error
+		if args[0] == "retry" {
+			err = store.MarkRetry(id)
+		} else {
+			err = store.Drop(id)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			failed = true
+			continue
+		}
+		if args[0] == "retry" {
+			fmt.Printf("%s queued for retry\n", id)
+		} else {
+			fmt.Printf("%s dropped\n", id)
+		}
+	}
+	if args[0] == "retry" && len(ids) > 0 {
+		fmt.Println("the running bridge picks up retries within 10 seconds")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// handleWebhook replays archived webhook payloads, either into the running
+// bridge (which parses them like a live delivery) or locally with --dry-run,
+// printing the events and statuses they produce.
+func handleWebhook(args []string) {
+	usage := "usage: kapso-whatsapp-cli webhook replay FILE|ID [--dry-run] [--url URL]"
+	if len(args) < 2 || args[0] != "replay" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	var target, url string
+	dryRun := false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--dry-run":
+			dryRun = true
+		case "--url":
+			if i+1 < len(args) {
+				url = args[i+1]
+				i++
+			}
+		default:
+			target = args[i]
+		}
+	}
+	if target == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// A file replays every record in it; anything else is a record ID in the
+	// bridge's archive.
+	var records []webhook.Record
+	if _, err := os.Stat(target); err == nil {
+		records, err = webhook.ReadRecords(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		rec, err := webhook.FindRecord(filepath.Join(cfg.State.Dir, "webhooks.jsonl"), target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v (is webhook.archive enabled?)\n", err)
+			os.Exit(1)
+		}
+		records = []webhook.Record{rec}
+	}
+
+	if dryRun {
+		replayLocally(records)
+		return
+	}
+
+	if url == "" {
+		url = cfg.Webhook.Addr
+		if !strings.Contains(url, "://") {
+			url = "http://localhost" + url
+		}
+		url += "/webhook"
+	}
+	failed := false
+	for _, rec := range records {
+		if err := replayToBridge(url, rec); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", rec.ID, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s replayed (%s, received %s)\n", rec.ID, rec.Format, rec.Time.Local().Format(time.RFC3339))
+	}
+	fmt.Println("messages the bridge already handled are dropped as duplicates; use --dry-run to inspect parsing")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// replayLocally parses records without a bridge: no media is downloaded and
+// nothing is sent.
+func replayLocally(records []webhook.Record) {
+	srv := &webhook.Server{
+		Extractor: &delivery.Extractor{ForwardReactions: true},
+		OnStatus: func(phoneNumberID string, st kapso.Status) {
+			fmt.Printf("  status  %s  %s  number=%s\n", st.ID, st.Status, phoneNumberID)
+		},
+	}
+	failed := false
+	for _, rec := range records {
+		fmt.Printf("%s (%s, received %s)\n", rec.ID, rec.Format, rec.Time.Local().Format(time.RFC3339))
+		out := make(chan delivery.Event)
+		done := make(chan struct{})
+		go func() {
+			for evt := range out {
+				fmt.Printf("  message %s  from %s %s  number=%s\n", evt.ID, evt.From, evt.Name, evt.PhoneNumberID)
+				if evt.ReplyTo != "" {
+					fmt.Printf("          in reply to %s\n", evt.ReplyTo)
+				}
+				fmt.Printf("          %s\n", strings.ReplaceAll(evt.Text, "\n", "\n          "))
+			}
+			close(done)
+		}()
+		err := srv.Replay(rec, out)
+		close(out)
+		<-done
+		if err != nil {
+			fmt.Printf("  error: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// replayToBridge posts an archived payload to the bridge's webhook endpoint
+// with its original headers, so the signature still validates.
+func replayToBridge(url string, rec webhook.Record) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(rec.Payload()))
+	if err != nil {
+		return err
+	}
+	for _, h := range []string{"Content-Type", "X-Webhook-Signature", "X-Hub-Signature-256"} {
+		if v := rec.Headers.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bridge unreachable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bridge answered %s", resp.Status)
+	}
+	return nil
 }
 
 func handlePreflight() {
@@ -136,11 +898,33 @@ func printUsage() {
 
 Commands:
   send --to +NUMBER --text "message"   Send a text message
+  send --to +NUMBER --file PATH [--caption "text"]
+                                        Send an image, document, audio or video
+  send-buttons --to +NUMBER --text "question" --button ID=Title [...]
+                                        Send up to 3 quick-reply buttons
+  send-list --to +NUMBER --text "question" --row ID=Title[=Description] [...]
+                                        Send a list message (up to 10 rows)
+  send-template --to +NUMBER --name NAME [--lang en_US] [--param VALUE ...]
+                                        Send an approved template (works outside the 24h window)
+  send-location --to +NUMBER --lat LAT --lng LNG [--name "Office"] [--address "..."]
+                                        Send a location pin
+  send-contact --to +NUMBER --name "Full Name" --phone +NUMBER [--email ADDRESS] [--company NAME]
+                                        Send a contact card
+  templates [--status approved]         List message templates on the business account
   status                                Check webhook server health
+  msg-status MESSAGE_ID                 Show delivery status (sent/delivered/read/failed) of a sent message
+  dlq list | show ID | retry ID|--all | drop ID|--all
+                                        Inspect, retry or drop messages the agent failed to answer
+  webhook replay FILE|ID [--dry-run] [--url URL]
+                                        Re-send archived webhook payloads to the bridge, or just parse them
   preflight                             Verify config, credentials, and connectivity
   help                                  Show this help
 
+Options:
+  --number NAME|ID                      Send from this [[numbers]] entry (default: the first)
+
 Configuration:
   Config file: ~/.config/kapso-whatsapp/config.toml (or set KAPSO_CONFIG)
-  Env vars KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID override config file values.`)
+  Env vars KAPSO_API_KEY and KAPSO_PHONE_NUMBER_ID override config file values.
+  KAPSO_BUSINESS_ACCOUNT_ID is only needed for the templates command.`)
 }
@@ -20,6 +20,92 @@ type Config struct {
 	Security   SecurityConfig   `toml:"security"`
 	Transcribe TranscribeConfig `toml:"transcribe"`
 	Commands   CommandsConfig   `toml:"commands"`
+	Outbound   OutboundConfig   `toml:"outbound"`
+	Media      MediaConfig      `toml:"media"`
+	Inbound    InboundConfig    `toml:"inbound"`
+	Numbers    []NumberConfig   `toml:"numbers"`
+}
+
+// NumberConfig is one WhatsApp business number served by the bridge
+// ([[numbers]]). Empty fields fall back to the top-level [kapso], [gateway]
+// and [security] settings; see PhoneNumbers.
+type NumberConfig struct {
+	Name          string              `toml:"name"` // label for logs, e.g. "support"
+	PhoneNumberID string              `toml:"phone_number_id"`
+	APIKey        string              `toml:"api_key"`
+	SessionKey    string              `toml:"session_key"`
+	GatewayURL    string              `toml:"gateway_url"`
+	DenyMessage   string              `toml:"deny_message"`
+	Roles         map[string][]string `toml:"roles"`
+}
+
+// PhoneNumbers returns the numbers the bridge serves, with defaults filled
+// in. Without [[numbers]] it is the single number from [kapso].
+func (c *Config) PhoneNumbers() []NumberConfig {
+	numbers := c.Numbers
+	if len(numbers) == 0 {
+		numbers = []NumberConfig{{PhoneNumberID: c.Kapso.PhoneNumberID}}
+	}
+
+	out := make([]NumberConfig, len(numbers))
+	for i, n := range numbers {
+		if n.APIKey == "" {
+			n.APIKey = c.Kapso.APIKey
+		}
+		if n.SessionKey == "" {
+			n.SessionKey = c.Gateway.SessionKey
+		}
+		if n.GatewayURL == "" {
+			n.GatewayURL = c.Gateway.URL
+		}
+		if n.DenyMessage == "" {
+			n.DenyMessage = c.Security.DenyMessage
+		}
+		if n.Roles == nil {
+			n.Roles = c.Security.Roles
+		}
+		if n.Name == "" {
+			n.Name = n.PhoneNumberID
+		}
+		out[i] = n
+	}
+	return out
+}
+
+// Security returns the security config for this number: the shared settings
+// with the number's roles and deny message.
+func (n NumberConfig) Security(base SecurityConfig) SecurityConfig {
+	base.Roles = n.Roles
+	base.DenyMessage = n.DenyMessage
+	return base
+}
+
+// InboundConfig bounds inbound processing. Messages from one sender are
+// handled in order, one at a time; MaxConcurrent caps how many senders are
+// served at once.
+type InboundConfig struct {
+	MaxConcurrent int    `toml:"max_concurrent"` // messages processed at once across senders
+	QueueLimit    int    `toml:"queue_limit"`    // messages waiting per sender before BusyMessage is sent
+	MaxQueued     int    `toml:"max_queued"`     // messages waiting across all senders before BusyMessage is sent
+	BusyMessage   string `toml:"busy_message"`   // sent when a sender's queue is full; empty = drop silently
+	DebounceMS    int    `toml:"debounce_ms"`    // merge a sender's messages arriving within this window; 0 = off
+}
+
+// MediaConfig controls the local store for inbound attachments
+// (state.dir/media). When enabled, images, documents and videos are forwarded
+// to the agent as file:// references instead of Kapso URLs, which only works
+// when the agent runs on the bridge host, so it is off by default.
+type MediaConfig struct {
+	Enabled        bool  `toml:"enabled"`
+	MaxSize        int64 `toml:"max_size"`        // bytes
+	RetentionHours int   `toml:"retention_hours"` // 0 = keep forever
+}
+
+// OutboundConfig paces messages the bridge sends, to stay within Meta's
+// per-number throughput and per-recipient (pair) rate limits.
+type OutboundConfig struct {
+	MessagesPerSecond   float64 `toml:"messages_per_second"`   // global cap; 0 = unlimited
+	RecipientIntervalMS int     `toml:"recipient_interval_ms"` // minimum gap between messages to one user
 }
 
 // CommandsConfig holds configuration for the bridge-level command system.
@@ -57,31 +143,58 @@ type TranscribeConfig struct {
 }
 
 type KapsoConfig struct {
-	APIKey        string `toml:"api_key"`
-	PhoneNumberID string `toml:"phone_number_id"`
+	APIKey            string `toml:"api_key"`
+	PhoneNumberID     string `toml:"phone_number_id"`
+	BusinessAccountID string `toml:"business_account_id"` // only needed to list templates
 }
 
 type DeliveryConfig struct {
 	Mode         string `toml:"mode"`
 	PollInterval int    `toml:"poll_interval"`
 	PollFallback bool   `toml:"poll_fallback"`
+	Reactions    string `toml:"reactions"`     // "forward" (default) or "drop"
+	QuoteReplies bool   `toml:"quote_replies"` // send the first reply chunk quoting the user's message
+	OnFailure    string `toml:"on_failure"`    // failed agent reply: "log" (default), "resend" or "alert"
+	AlertTo      string `toml:"alert_to"`      // number alerted when on_failure = "alert"
+	DedupTTL     int    `toml:"dedup_ttl"`     // hours a message ID is remembered for dedup
 }
 
 type WebhookConfig struct {
 	Addr        string `toml:"addr"`
 	VerifyToken string `toml:"verify_token"`
 	Secret      string `toml:"secret"`
+	Workers     int    `toml:"workers"`    // goroutines processing accepted payloads
+	QueueSize   int    `toml:"queue_size"` // payloads waiting before the server answers 503
+
+	// Archive keeps every verified payload in <state.dir>/webhooks.jsonl,
+	// rotated at ArchiveMaxSize bytes.
+	Archive        bool  `toml:"archive"`
+	ArchiveMaxSize int64 `toml:"archive_max_size"`
 }
 
 type GatewayConfig struct {
-	Type         string   `toml:"type"` // "openclaw" (default) or "zeroclaw"
+	Type         string   `toml:"type"` // "openclaw" (default), "zeroclaw", "openai" or "http"
 	URL          string   `toml:"url"`
 	Token        string   `toml:"token"`
-	SessionKey   string   `toml:"session_key"`   // OpenClaw only
+	SessionKey   string   `toml:"session_key"`   // OpenClaw and OpenAI
 	SessionsJSON string   `toml:"sessions_json"` // OpenClaw only
 	ErrorMessage string   `toml:"error_message"` // sent to WhatsApp when agent fails
 	Role         string   `toml:"role"`          // OpenClaw role, default "operator"
 	Scopes       []string `toml:"scopes"`        // OpenClaw scopes, default ["operator.read","operator.write"]
+
+	// OpenAI-compatible chat completions (type = "openai"); URL is the base
+	// URL including /v1.
+	Model         string `toml:"model"`          // empty = first model the endpoint lists
+	SystemPrompt  string `toml:"system_prompt"`  // {name}, {from} and {role} are filled in per sender
+	HistoryTurns  int    `toml:"history_turns"`  // exchanges kept per session; 0 = no history
+	HistoryTokens int    `toml:"history_tokens"` // estimated token budget for prompt, history and message; 0 = unlimited
+	Stream        bool   `toml:"stream"`         // stream the completion
+
+	// Plain HTTP agent (type = "http"); URL is the endpoint messages are
+	// POSTed to.
+	Secret       string `toml:"secret"`        // HMAC-SHA256 key signing requests and callbacks
+	CallbackAddr string `toml:"callback_addr"` // listen address for async replies (needs Secret); empty = synchronous replies only
+	CallbackURL  string `toml:"callback_url"`  // URL sent to the agent; empty = derived from callback_addr
 }
 
 type StateConfig struct {
@@ -104,9 +217,15 @@ func defaults() Config {
 		Delivery: DeliveryConfig{
 			Mode:         "polling",
 			PollInterval: 30,
+			Reactions:    "forward",
+			OnFailure:    "log",
+			DedupTTL:     24,
 		},
 		Webhook: WebhookConfig{
-			Addr: ":18790",
+			Addr:           ":18790",
+			Workers:        4,
+			QueueSize:      100,
+			ArchiveMaxSize: 10 * 1024 * 1024,
 		},
 		Gateway: GatewayConfig{
 			URL:          "ws://127.0.0.1:18789",
@@ -115,6 +234,9 @@ func defaults() Config {
 			ErrorMessage: "Sorry, I ran into an issue processing your message. Please try again in a moment.",
 			Role:         "operator",
 			Scopes:       []string{"operator.read", "operator.write"},
+			SystemPrompt: "You are a helpful assistant answering WhatsApp messages. You are talking to {name} ({from}), whose role is {role}. Keep replies short and use plain text.",
+			HistoryTurns: 20,
+			CallbackAddr: "127.0.0.1:18791",
 		},
 		State: StateConfig{
 			Dir: filepath.Join(home, ".config", "kapso-whatsapp"),
@@ -127,6 +249,20 @@ func defaults() Config {
 			SessionIsolation: true,
 			DefaultRole:      "member",
 		},
+		Outbound: OutboundConfig{
+			MessagesPerSecond:   20,
+			RecipientIntervalMS: 500,
+		},
+		Inbound: InboundConfig{
+			MaxConcurrent: 8,
+			QueueLimit:    5,
+			MaxQueued:     500,
+			BusyMessage:   "I'm still working on your earlier messages. Please send this one again in a moment.",
+		},
+		Media: MediaConfig{
+			MaxSize:        50 * 1024 * 1024, // 50MB
+			RetentionHours: 72,
+		},
 		Transcribe: TranscribeConfig{
 			MaxAudioSize:      25 * 1024 * 1024, // 25MB
 			BinaryPath:        "whisper-cli",
@@ -177,6 +313,9 @@ func applyEnv(cfg *Config) {
 	if v := os.Getenv("KAPSO_PHONE_NUMBER_ID"); v != "" {
 		cfg.Kapso.PhoneNumberID = v
 	}
+	if v := os.Getenv("KAPSO_BUSINESS_ACCOUNT_ID"); v != "" {
+		cfg.Kapso.BusinessAccountID = v
+	}
 
 	if v := os.Getenv("KAPSO_MODE"); v != "" {
 		cfg.Delivery.Mode = resolveMode(v, "")
@@ -191,10 +330,94 @@ func applyEnv(cfg *Config) {
 	if v := os.Getenv("KAPSO_POLL_FALLBACK"); v != "" {
 		cfg.Delivery.PollFallback = v == "true"
 	}
+	if v := os.Getenv("KAPSO_REACTIONS"); v != "" {
+		cfg.Delivery.Reactions = strings.ToLower(v)
+	}
+	if v := os.Getenv("KAPSO_QUOTE_REPLIES"); v != "" {
+		cfg.Delivery.QuoteReplies = v == "true"
+	}
+	if v := os.Getenv("KAPSO_ON_FAILURE"); v != "" {
+		cfg.Delivery.OnFailure = strings.ToLower(v)
+	}
+	if v := os.Getenv("KAPSO_ALERT_TO"); v != "" {
+		cfg.Delivery.AlertTo = v
+	}
+	if v := os.Getenv("KAPSO_DEDUP_TTL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Delivery.DedupTTL = n
+		}
+	}
+
+	if v := os.Getenv("KAPSO_OUTBOUND_RATE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Outbound.MessagesPerSecond = f
+		}
+	}
+	if v := os.Getenv("KAPSO_OUTBOUND_RECIPIENT_INTERVAL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Outbound.RecipientIntervalMS = n
+		}
+	}
+
+	if v := os.Getenv("KAPSO_INBOUND_MAX_CONCURRENT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Inbound.MaxConcurrent = n
+		}
+	}
+	if v := os.Getenv("KAPSO_INBOUND_QUEUE_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Inbound.QueueLimit = n
+		}
+	}
+	if v := os.Getenv("KAPSO_INBOUND_MAX_QUEUED"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Inbound.MaxQueued = n
+		}
+	}
+	if v := os.Getenv("KAPSO_INBOUND_BUSY_MESSAGE"); v != "" {
+		cfg.Inbound.BusyMessage = v
+	}
+	if v := os.Getenv("KAPSO_INBOUND_DEBOUNCE_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Inbound.DebounceMS = n
+		}
+	}
+
+	if v := os.Getenv("KAPSO_MEDIA_ENABLED"); v != "" {
+		cfg.Media.Enabled = v == "true"
+	}
+	if v := os.Getenv("KAPSO_MEDIA_MAX_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			cfg.Media.MaxSize = n
+		}
+	}
+	if v := os.Getenv("KAPSO_MEDIA_RETENTION_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Media.RetentionHours = n
+		}
+	}
 
 	if v := os.Getenv("KAPSO_WEBHOOK_ADDR"); v != "" {
 		cfg.Webhook.Addr = v
 	}
+	if v := os.Getenv("KAPSO_WEBHOOK_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Webhook.Workers = n
+		}
+	}
+	if v := os.Getenv("KAPSO_WEBHOOK_QUEUE_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Webhook.QueueSize = n
+		}
+	}
+	if v := os.Getenv("KAPSO_WEBHOOK_ARCHIVE"); v != "" {
+		cfg.Webhook.Archive = v == "true"
+	}
+	if v := os.Getenv("KAPSO_WEBHOOK_ARCHIVE_MAX_SIZE"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			cfg.Webhook.ArchiveMaxSize = n
+		}
+	}
 	if v := os.Getenv("KAPSO_WEBHOOK_VERIFY_TOKEN"); v != "" {
 		cfg.Webhook.VerifyToken = v
 	}
@@ -236,6 +459,18 @@ func applyEnv(cfg *Config) {
 		}
 		cfg.Gateway.Scopes = parts
 	}
+	if v := os.Getenv("GATEWAY_MODEL"); v != "" {
+		cfg.Gateway.Model = v
+	}
+	if v := os.Getenv("GATEWAY_SYSTEM_PROMPT"); v != "" {
+		cfg.Gateway.SystemPrompt = v
+	}
+	if v := os.Getenv("GATEWAY_SECRET"); v != "" {
+		cfg.Gateway.Secret = v
+	}
+	if v := os.Getenv("GATEWAY_CALLBACK_URL"); v != "" {
+		cfg.Gateway.CallbackURL = v
+	}
 
 	if v := os.Getenv("KAPSO_STATE_DIR"); v != "" {
 		cfg.State.Dir = v
@@ -352,6 +587,34 @@ func (c *Config) Validate() error {
 		c.Delivery.Mode = "polling"
 	}
 
+	switch c.Delivery.Reactions {
+	case "forward", "drop":
+	default:
+		c.Delivery.Reactions = "forward"
+	}
+
+	if c.Delivery.DedupTTL <= 0 {
+		c.Delivery.DedupTTL = 24
+	}
+
+	switch c.Delivery.OnFailure {
+	case "log", "resend":
+	case "alert":
+		if c.Delivery.AlertTo == "" {
+			log.Printf("warning: delivery.on_failure = \"alert\" without alert_to — falling back to \"log\"")
+			c.Delivery.OnFailure = "log"
+		}
+	default:
+		c.Delivery.OnFailure = "log"
+	}
+
+	if c.Outbound.MessagesPerSecond < 0 {
+		c.Outbound.MessagesPerSecond = 0
+	}
+	if c.Outbound.RecipientIntervalMS < 0 {
+		c.Outbound.RecipientIntervalMS = 0
+	}
+
 	// Security validation.
 	switch c.Security.Mode {
 	case "allowlist", "open":
@@ -388,6 +651,23 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Numbers validation: every [[numbers]] entry needs its own phone number ID.
+	seenIDs := make(map[string]bool)
+	numbers := c.Numbers[:0]
+	for i, n := range c.Numbers {
+		if n.PhoneNumberID == "" {
+			log.Printf("warning: [[numbers]] entry %d has no phone_number_id — ignored", i+1)
+			continue
+		}
+		if seenIDs[n.PhoneNumberID] {
+			log.Printf("warning: phone number %s is configured twice — the first entry wins", n.PhoneNumberID)
+			continue
+		}
+		seenIDs[n.PhoneNumberID] = true
+		numbers = append(numbers, n)
+	}
+	c.Numbers = numbers
+
 	// Gateway validation: reset empty role/scopes to defaults.
 	if c.Gateway.Role == "" {
 		c.Gateway.Role = "operator"
@@ -395,6 +675,26 @@ func (c *Config) Validate() error {
 	if len(c.Gateway.Scopes) == 0 {
 		c.Gateway.Scopes = []string{"operator.read", "operator.write"}
 	}
+	if c.Gateway.Type == "openai" {
+		if !strings.HasPrefix(c.Gateway.URL, "http://") && !strings.HasPrefix(c.Gateway.URL, "https://") {
+			log.Printf("warning: gateway.type = \"openai\" needs an http(s) gateway.url such as http://127.0.0.1:8080/v1, got %q", c.Gateway.URL)
+		}
+		if c.Gateway.HistoryTurns < 0 {
+			log.Printf("warning: gateway.history_turns %d is negative, keeping no history", c.Gateway.HistoryTurns)
+			c.Gateway.HistoryTurns = 0
+		}
+		if c.Gateway.HistoryTokens < 0 {
+			c.Gateway.HistoryTokens = 0
+		}
+	}
+	if c.Gateway.Type == "http" {
+		if !strings.HasPrefix(c.Gateway.URL, "http://") && !strings.HasPrefix(c.Gateway.URL, "https://") {
+			log.Printf("warning: gateway.type = \"http\" needs an http(s) gateway.url, got %q", c.Gateway.URL)
+		}
+		if c.Gateway.Secret == "" {
+			log.Printf("warning: gateway.secret is not set — requests to the agent are unsigned and async callbacks are disabled")
+		}
+	}
 
 	// Transcribe validation: reset MaxAudioSize if zero or negative (guards TOML zero-value masking).
 	if c.Transcribe.MaxAudioSize <= 0 {
@@ -404,6 +704,39 @@ func (c *Config) Validate() error {
 		c.Transcribe.CacheTTL = 3600
 	}
 
+	// Webhook validation: the worker pool needs at least one worker and slot.
+	if c.Webhook.Workers <= 0 {
+		c.Webhook.Workers = 4
+	}
+	if c.Webhook.QueueSize <= 0 {
+		c.Webhook.QueueSize = 100
+	}
+	if c.Webhook.ArchiveMaxSize <= 0 {
+		c.Webhook.ArchiveMaxSize = 10 * 1024 * 1024
+	}
+
+	// Inbound validation: at least one worker; queue limits of 0 mean unlimited.
+	if c.Inbound.MaxConcurrent <= 0 {
+		c.Inbound.MaxConcurrent = 8
+	}
+	if c.Inbound.QueueLimit < 0 {
+		c.Inbound.QueueLimit = 0
+	}
+	if c.Inbound.MaxQueued < 0 {
+		c.Inbound.MaxQueued = 0
+	}
+	if c.Inbound.DebounceMS < 0 {
+		c.Inbound.DebounceMS = 0
+	}
+
+	// Media validation.
+	if c.Media.MaxSize <= 0 {
+		c.Media.MaxSize = 50 * 1024 * 1024
+	}
+	if c.Media.RetentionHours < 0 {
+		c.Media.RetentionHours = 0
+	}
+
 	// Commands validation.
 	if len(c.Commands.Definitions) > 0 {
 		if c.Commands.Prefix == "" {
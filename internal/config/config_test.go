@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -305,3 +306,367 @@ func TestTranscribeEmptyProviderNoError(t *testing.T) {
 		t.Errorf("Provider should be empty, got %q", cfg.Transcribe.Provider)
 	}
 }
+
+// TestDeliveryReactions verifies the reactions default, env override, and
+// that Validate() resets unknown values to "forward".
+func TestDeliveryReactions(t *testing.T) {
+	cfg := defaults()
+	if cfg.Delivery.Reactions != "forward" {
+		t.Errorf("Reactions default: got %q, want %q", cfg.Delivery.Reactions, "forward")
+	}
+
+	t.Setenv("KAPSO_REACTIONS", "DROP")
+	applyEnv(&cfg)
+	if cfg.Delivery.Reactions != "drop" {
+		t.Errorf("Reactions from env: got %q, want %q", cfg.Delivery.Reactions, "drop")
+	}
+
+	cfg.Delivery.Reactions = "sometimes"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Delivery.Reactions != "forward" {
+		t.Errorf("Reactions after Validate: got %q, want %q", cfg.Delivery.Reactions, "forward")
+	}
+}
+
+// TestDeliveryQuoteReplies verifies quote_replies is off by default and can
+// be enabled from the environment.
+func TestDeliveryQuoteReplies(t *testing.T) {
+	cfg := defaults()
+	if cfg.Delivery.QuoteReplies {
+		t.Error("QuoteReplies default: got true, want false")
+	}
+
+	t.Setenv("KAPSO_QUOTE_REPLIES", "true")
+	applyEnv(&cfg)
+	if !cfg.Delivery.QuoteReplies {
+		t.Error("QuoteReplies from env: got false, want true")
+	}
+}
+
+// TestOutboundConfig verifies outbound pacing defaults, env overrides, and
+// that Validate() clamps negative values to 0 (unlimited).
+func TestOutboundConfig(t *testing.T) {
+	cfg := defaults()
+	if cfg.Outbound.MessagesPerSecond != 20 || cfg.Outbound.RecipientIntervalMS != 500 {
+		t.Errorf("Outbound defaults: got %+v", cfg.Outbound)
+	}
+
+	t.Setenv("KAPSO_OUTBOUND_RATE", "2.5")
+	t.Setenv("KAPSO_OUTBOUND_RECIPIENT_INTERVAL_MS", "1000")
+	applyEnv(&cfg)
+	if cfg.Outbound.MessagesPerSecond != 2.5 || cfg.Outbound.RecipientIntervalMS != 1000 {
+		t.Errorf("Outbound from env: got %+v", cfg.Outbound)
+	}
+
+	cfg.Outbound.MessagesPerSecond = -1
+	cfg.Outbound.RecipientIntervalMS = -5
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Outbound.MessagesPerSecond != 0 || cfg.Outbound.RecipientIntervalMS != 0 {
+		t.Errorf("Outbound after Validate: got %+v", cfg.Outbound)
+	}
+}
+
+// TestMediaConfig verifies media store defaults, env overrides, and that
+// Validate() restores a usable size limit.
+func TestMediaConfig(t *testing.T) {
+	cfg := defaults()
+	if cfg.Media.Enabled || cfg.Media.MaxSize != 50*1024*1024 || cfg.Media.RetentionHours != 72 {
+		t.Errorf("Media defaults: got %+v", cfg.Media)
+	}
+
+	t.Setenv("KAPSO_MEDIA_ENABLED", "true")
+	t.Setenv("KAPSO_MEDIA_MAX_SIZE", "1024")
+	t.Setenv("KAPSO_MEDIA_RETENTION_HOURS", "24")
+	applyEnv(&cfg)
+	if !cfg.Media.Enabled || cfg.Media.MaxSize != 1024 || cfg.Media.RetentionHours != 24 {
+		t.Errorf("Media from env: got %+v", cfg.Media)
+	}
+
+	cfg.Media.MaxSize = 0
+	cfg.Media.RetentionHours = -1
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Media.MaxSize != 50*1024*1024 || cfg.Media.RetentionHours != 0 {
+		t.Errorf("Media after Validate: got %+v", cfg.Media)
+	}
+}
+
+// TestDeliveryOnFailure verifies the failed-delivery action defaults to "log",
+// that unknown values and "alert" without alert_to fall back to it.
+func TestDeliveryOnFailure(t *testing.T) {
+	cfg := defaults()
+	if cfg.Delivery.OnFailure != "log" {
+		t.Errorf("OnFailure default: got %q, want %q", cfg.Delivery.OnFailure, "log")
+	}
+
+	cfg.Delivery.OnFailure = "bogus"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Delivery.OnFailure != "log" {
+		t.Errorf("OnFailure after Validate: got %q, want %q", cfg.Delivery.OnFailure, "log")
+	}
+
+	t.Setenv("KAPSO_ON_FAILURE", "ALERT")
+	applyEnv(&cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Delivery.OnFailure != "log" {
+		t.Errorf("alert without alert_to: got %q, want %q", cfg.Delivery.OnFailure, "log")
+	}
+
+	t.Setenv("KAPSO_ALERT_TO", "+15551234567")
+	applyEnv(&cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Delivery.OnFailure != "alert" || cfg.Delivery.AlertTo != "+15551234567" {
+		t.Errorf("got %+v", cfg.Delivery)
+	}
+}
+
+// TestDeliveryDedupTTL verifies the dedup window default, env override, and
+// that Validate() restores the default for non-positive values.
+func TestDeliveryDedupTTL(t *testing.T) {
+	cfg := defaults()
+	if cfg.Delivery.DedupTTL != 24 {
+		t.Errorf("DedupTTL default: got %d, want 24", cfg.Delivery.DedupTTL)
+	}
+
+	t.Setenv("KAPSO_DEDUP_TTL", "48")
+	applyEnv(&cfg)
+	if cfg.Delivery.DedupTTL != 48 {
+		t.Errorf("DedupTTL from env: got %d, want 48", cfg.Delivery.DedupTTL)
+	}
+
+	cfg.Delivery.DedupTTL = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Delivery.DedupTTL != 24 {
+		t.Errorf("DedupTTL after Validate: got %d, want 24", cfg.Delivery.DedupTTL)
+	}
+}
+
+// TestInboundConfig verifies inbound concurrency defaults, env overrides, and
+// Validate() clamping.
+func TestInboundConfig(t *testing.T) {
+	cfg := defaults()
+	if cfg.Inbound.MaxConcurrent != 8 || cfg.Inbound.QueueLimit != 5 || cfg.Inbound.MaxQueued != 500 || cfg.Inbound.BusyMessage == "" {
+		t.Errorf("Inbound defaults: got %+v", cfg.Inbound)
+	}
+
+	t.Setenv("KAPSO_INBOUND_MAX_CONCURRENT", "2")
+	t.Setenv("KAPSO_INBOUND_QUEUE_LIMIT", "10")
+	t.Setenv("KAPSO_INBOUND_MAX_QUEUED", "50")
+	t.Setenv("KAPSO_INBOUND_BUSY_MESSAGE", "one moment")
+	t.Setenv("KAPSO_INBOUND_DEBOUNCE_MS", "3000")
+	applyEnv(&cfg)
+	if cfg.Inbound.MaxConcurrent != 2 || cfg.Inbound.QueueLimit != 10 || cfg.Inbound.MaxQueued != 50 || cfg.Inbound.BusyMessage != "one moment" || cfg.Inbound.DebounceMS != 3000 {
+		t.Errorf("Inbound from env: got %+v", cfg.Inbound)
+	}
+
+	cfg.Inbound.MaxConcurrent = 0
+	cfg.Inbound.QueueLimit = -1
+	cfg.Inbound.MaxQueued = -1
+	cfg.Inbound.DebounceMS = -1
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Inbound.MaxConcurrent != 8 || cfg.Inbound.QueueLimit != 0 || cfg.Inbound.MaxQueued != 0 || cfg.Inbound.DebounceMS != 0 {
+		t.Errorf("Inbound after Validate: got %+v", cfg.Inbound)
+	}
+}
+
+// TestWebhookWorkers verifies webhook pool defaults, env overrides, and that
+// Validate() restores non-positive values.
+func TestWebhookWorkers(t *testing.T) {
+	cfg := defaults()
+	if cfg.Webhook.Workers != 4 || cfg.Webhook.QueueSize != 100 {
+		t.Errorf("Webhook defaults: got %+v", cfg.Webhook)
+	}
+
+	t.Setenv("KAPSO_WEBHOOK_WORKERS", "16")
+	t.Setenv("KAPSO_WEBHOOK_QUEUE_SIZE", "500")
+	applyEnv(&cfg)
+	if cfg.Webhook.Workers != 16 || cfg.Webhook.QueueSize != 500 {
+		t.Errorf("Webhook from env: got %+v", cfg.Webhook)
+	}
+
+	cfg.Webhook.Workers = 0
+	cfg.Webhook.QueueSize = -1
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Webhook.Workers != 4 || cfg.Webhook.QueueSize != 100 {
+		t.Errorf("Webhook after Validate: got %+v", cfg.Webhook)
+	}
+}
+
+// TestWebhookArchive verifies the payload archive is off by default, can be
+// enabled from the environment, and always has a rotation size.
+func TestWebhookArchive(t *testing.T) {
+	cfg := defaults()
+	if cfg.Webhook.Archive || cfg.Webhook.ArchiveMaxSize != 10*1024*1024 {
+		t.Errorf("archive defaults: got %+v", cfg.Webhook)
+	}
+
+	t.Setenv("KAPSO_WEBHOOK_ARCHIVE", "true")
+	t.Setenv("KAPSO_WEBHOOK_ARCHIVE_MAX_SIZE", "1048576")
+	applyEnv(&cfg)
+	if !cfg.Webhook.Archive || cfg.Webhook.ArchiveMaxSize != 1048576 {
+		t.Errorf("archive from env: got %+v", cfg.Webhook)
+	}
+
+	cfg.Webhook.ArchiveMaxSize = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Webhook.ArchiveMaxSize != 10*1024*1024 {
+		t.Errorf("ArchiveMaxSize after Validate = %d", cfg.Webhook.ArchiveMaxSize)
+	}
+}
+
+// TestPhoneNumbers verifies that a config without [[numbers]] serves the
+// [kapso] number, and that [[numbers]] entries inherit unset fields.
+func TestPhoneNumbers(t *testing.T) {
+	cfg := defaults()
+	cfg.Kapso.APIKey = "main-key"
+	cfg.Kapso.PhoneNumberID = "111"
+	cfg.Security.Roles = map[string][]string{"admin": {"+1555"}}
+
+	nums := cfg.PhoneNumbers()
+	if len(nums) != 1 || nums[0].PhoneNumberID != "111" || nums[0].APIKey != "main-key" {
+		t.Fatalf("single number: got %+v", nums)
+	}
+	if nums[0].SessionKey != "main" || nums[0].GatewayURL != cfg.Gateway.URL || nums[0].Name != "111" {
+		t.Errorf("single number defaults: got %+v", nums[0])
+	}
+
+	tomlContent := `
+[[numbers]]
+name = "support"
+phone_number_id = "222"
+session_key = "support"
+deny_message = "Support only."
+
+[[numbers]]
+name = "sales"
+phone_number_id = "333"
+api_key = "sales-key"
+gateway_url = "ws://sales:18789"
+roles = { member = ["+1666"] }
+
+[[numbers]]
+name = "broken"
+
+[[numbers]]
+name = "support-copy"
+phone_number_id = "222"
+`
+	cfgFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(cfgFile, []byte(tomlContent), 0o600); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+	t.Setenv("KAPSO_CONFIG", cfgFile)
+	t.Setenv("KAPSO_API_KEY", "main-key")
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	nums = loaded.PhoneNumbers()
+	if len(nums) != 2 {
+		t.Fatalf("expected 2 numbers (entry without ID and duplicate dropped), got %+v", nums)
+	}
+	if nums[0].Name != "support" {
+		t.Errorf("duplicate phone number: got %q, want the first entry", nums[0].Name)
+	}
+	support, sales := nums[0], nums[1]
+	if support.APIKey != "main-key" || support.SessionKey != "support" || support.GatewayURL != loaded.Gateway.URL {
+		t.Errorf("support: got %+v", support)
+	}
+	if sec := support.Security(loaded.Security); sec.DenyMessage != "Support only." || sec.RateLimit != loaded.Security.RateLimit {
+		t.Errorf("support security: got %+v", sec)
+	}
+	if sales.APIKey != "sales-key" || sales.GatewayURL != "ws://sales:18789" || sales.SessionKey != "main" {
+		t.Errorf("sales: got %+v", sales)
+	}
+	if got := sales.Security(loaded.Security).Roles["member"]; len(got) != 1 || got[0] != "+1666" {
+		t.Errorf("sales roles: got %v", got)
+	}
+}
+
+// TestOpenAIGateway verifies the openai gateway options load from TOML and
+// the environment, and that negative budgets are cleared.
+func TestOpenAIGateway(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	content := `
+[gateway]
+type = "openai"
+url = "http://127.0.0.1:8080/v1"
+model = "llama-3"
+history_turns = -1
+history_tokens = 4000
+stream = true
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KAPSO_CONFIG", path)
+	t.Setenv("GATEWAY_SYSTEM_PROMPT", "You help {name}.")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	gw := cfg.Gateway
+	if gw.Type != "openai" || gw.Model != "llama-3" || !gw.Stream || gw.HistoryTokens != 4000 {
+		t.Errorf("gateway: got %+v", gw)
+	}
+	if gw.SystemPrompt != "You help {name}." {
+		t.Errorf("SystemPrompt = %q, want the env override", gw.SystemPrompt)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Gateway.HistoryTurns != 0 {
+		t.Errorf("HistoryTurns = %d, want 0 after Validate", cfg.Gateway.HistoryTurns)
+	}
+
+	if d := defaults(); d.Gateway.HistoryTurns != 20 || !strings.Contains(d.Gateway.SystemPrompt, "{role}") {
+		t.Errorf("defaults: got %+v", d.Gateway)
+	}
+}
+
+// TestHTTPGateway verifies the http gateway listens for callbacks on loopback
+// and takes its secret and callback URL from the environment.
+func TestHTTPGateway(t *testing.T) {
+	cfg := defaults()
+	if cfg.Gateway.CallbackAddr != "127.0.0.1:18791" || cfg.Gateway.Secret != "" {
+		t.Errorf("defaults: got %+v", cfg.Gateway)
+	}
+
+	t.Setenv("GATEWAY_TYPE", "http")
+	t.Setenv("GATEWAY_URL", "https://agent.example.com/messages")
+	t.Setenv("GATEWAY_SECRET", "s3cret")
+	t.Setenv("GATEWAY_CALLBACK_URL", "https://bridge.example.com/callback")
+	applyEnv(&cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	gw := cfg.Gateway
+	if gw.Type != "http" || gw.Secret != "s3cret" || gw.CallbackURL != "https://bridge.example.com/callback" {
+		t.Errorf("from env: got %+v", gw)
+	}
+}
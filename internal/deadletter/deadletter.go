@@ -0,0 +1,189 @@
+// Package deadletter keeps inbound messages the agent failed to answer, so an
+// operator can retry or drop them once the agent is healthy again. Each entry
+// is a JSON file in Dir, which lets the CLI and the running bridge share the
+// store without further coordination: the CLI marks entries for retry and the
+// bridge picks them up.
+package deadletter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// Failure kinds.
+const (
+	KindTimeout = "timeout" // the agent did not answer in time
+	KindGateway = "gateway" // the gateway rejected the request or the connection failed
+	KindSend    = "send"    // the reply could not be sent to WhatsApp
+)
+
+// ErrNotFound is returned for an unknown message ID.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Entry is one failed event.
+type Entry struct {
+	Event       delivery.Event `json:"event"`
+	Kind        string         `json:"kind"`
+	Error       string         `json:"error"`
+	Attempts    int            `json:"attempts"`
+	FirstFailed time.Time      `json:"first_failed"`
+	LastFailed  time.Time      `json:"last_failed"`
+	Retry       bool           `json:"retry,omitempty"` // marked for retry by the operator
+}
+
+// Store is a directory of dead-letter entries.
+type Store struct {
+	Dir string
+}
+
+// Add records a failure of evt. A repeated failure of the same message
+// increments its attempt count.
+func (s *Store) Add(evt delivery.Event, kind string, cause error) error {
+	now := time.Now().UTC()
+	e, err := s.Get(evt.ID)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		e = Entry{FirstFailed: now}
+	}
+	e.Event = evt
+	e.Kind = kind
+	e.Error = cause.Error()
+	e.Attempts++
+	e.LastFailed = now
+	e.Retry = false
+	return s.write(e)
+}
+
+// Get returns the entry for message id.
+func (s *Store) Get(id string) (Entry, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Entry{}, fmt.Errorf("%s: %w", id, ErrNotFound)
+		}
+		return Entry{}, fmt.Errorf("read dead letter: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("decode dead letter %s: %w", id, err)
+	}
+	return e, nil
+}
+
+// List returns all entries, oldest failure first.
+func (s *Store) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read dead-letter dir: %w", err)
+	}
+
+	var out []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].FirstFailed.Before(out[b].FirstFailed) })
+	return out, nil
+}
+
+// MarkRetry flags the entry for message id to be retried by the bridge.
+func (s *Store) MarkRetry(id string) error {
+	e, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	e.Retry = true
+	return s.write(e)
+}
+
+// Drop deletes the entry for message id.
+func (s *Store) Drop(id string) error {
+	if err := os.Remove(s.path(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", id, ErrNotFound)
+		}
+		return fmt.Errorf("drop dead letter: %w", err)
+	}
+	return nil
+}
+
+// TakeRetries returns the entries marked for retry and clears their mark. The
+// bridge drops an entry once its retry succeeds; a retry that fails again
+// updates the entry with a higher attempt count.
+func (s *Store) TakeRetries() ([]Entry, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	for _, e := range all {
+		if !e.Retry {
+			continue
+		}
+		e.Retry = false
+		if err := s.write(e); err != nil {
+			return out, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// write stores e atomically, so a concurrent reader never sees a partial file.
+func (s *Store) write(e Entry) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("create dead-letter dir: %w", err)
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(e.Event.ID)); err != nil {
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	return nil
+}
+
+// path names entries by a hash of the message ID, which may contain
+// characters that are not safe in file names.
+func (s *Store) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:16])+".json")
+}
@@ -0,0 +1,85 @@
+package deadletter
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+func TestAddAndGet(t *testing.T) {
+	s := &Store{Dir: filepath.Join(t.TempDir(), "deadletter")}
+	evt := delivery.Event{ID: "wamid.HBgL+/==", From: "+1555", Text: "book a table"}
+
+	if err := s.Add(evt, KindTimeout, errors.New("context deadline exceeded")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(evt, KindGateway, errors.New("agent unavailable")); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := s.Get(evt.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Attempts != 2 || e.Kind != KindGateway || e.Error != "agent unavailable" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e.Event.Text != "book a table" || e.FirstFailed.After(e.LastFailed) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	if _, err := s.Get("wamid.unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get unknown: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestListDropAndRetry(t *testing.T) {
+	s := &Store{Dir: filepath.Join(t.TempDir(), "deadletter")}
+
+	if got, err := s.List(); err != nil || len(got) != 0 {
+		t.Fatalf("List on missing dir: %v, %v", got, err)
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := s.Add(delivery.Event{ID: id}, KindSend, errors.New("send failed")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.List()
+	if err != nil || len(got) != 3 {
+		t.Fatalf("List: %d entries, %v", len(got), err)
+	}
+
+	if err := s.Drop("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Drop("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Drop: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.MarkRetry("c"); err != nil {
+		t.Fatal(err)
+	}
+	retries, err := s.TakeRetries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retries) != 1 || retries[0].Event.ID != "c" {
+		t.Fatalf("TakeRetries = %+v, want only c", retries)
+	}
+
+	if again, _ := s.TakeRetries(); len(again) != 0 {
+		t.Errorf("retry handed out twice: %+v", again)
+	}
+
+	// A failed retry counts as another attempt.
+	if err := s.Add(retries[0].Event, KindGateway, errors.New("still down")); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := s.Get("c")
+	if e.Retry || e.Attempts != 2 {
+		t.Errorf("entry after failed retry = %+v", e)
+	}
+}
@@ -0,0 +1,157 @@
+package delivery
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDedupTTL is how long a message ID is remembered. Meta retries
+// webhook deliveries for up to a day in practice.
+const DefaultDedupTTL = 24 * time.Hour
+
+// Dedup remembers message IDs for a TTL window. With a Path, every ID is
+// appended to a file as it is seen and reloaded on start, so a restart does
+// not let a webhook redelivery or a poller/webhook overlap through.
+type Dedup struct {
+	path string
+	ttl  time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // ID → first seen
+	now  func() time.Time
+}
+
+// NewDedup loads the dedup file at path (created on first use), dropping
+// entries older than ttl. An empty path keeps IDs in memory only; ttl <= 0
+// means DefaultDedupTTL.
+func NewDedup(path string, ttl time.Duration) (*Dedup, error) {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+	d := &Dedup{path: path, ttl: ttl, seen: make(map[string]time.Time), now: time.Now}
+	if path == "" {
+		return d, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return nil, fmt.Errorf("open dedup file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	cutoff := d.now().Add(-ttl)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		id, ts, ok := strings.Cut(sc.Text(), "\t")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		if t := time.Unix(n, 0); t.After(cutoff) {
+			d.seen[id] = t
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read dedup file: %w", err)
+	}
+	return d, nil
+}
+
+// Seen reports whether id was already seen within the TTL window, and marks
+// it as seen if not.
+func (d *Dedup) Seen(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if t, ok := d.seen[id]; ok && now.Sub(t) < d.ttl {
+		return true
+	}
+	d.seen[id] = now
+
+	if d.path != "" {
+		if err := d.appendLocked(id, now); err != nil {
+			// Still deduplicated in memory; only restart safety is lost.
+			log.Printf("dedup: %v", err)
+		}
+	}
+	return false
+}
+
+// Prune forgets IDs older than the TTL and compacts the file. It returns the
+// number of IDs removed.
+func (d *Dedup) Prune() (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := d.now().Add(-d.ttl)
+	removed := 0
+	for id, t := range d.seen {
+		if !t.After(cutoff) {
+			delete(d.seen, id)
+			removed++
+		}
+	}
+	if d.path == "" || removed == 0 {
+		return removed, nil
+	}
+	return removed, d.rewriteLocked()
+}
+
+// Len returns the number of remembered IDs.
+func (d *Dedup) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.seen)
+}
+
+func (d *Dedup) appendLocked(id string, t time.Time) error {
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open dedup file: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s\t%d\n", id, t.Unix()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write dedup file: %w", err)
+	}
+	return f.Close()
+}
+
+// rewriteLocked replaces the file with the live entries via a temp file, so
+// a crash mid-write never loses the whole set.
+func (d *Dedup) rewriteLocked() error {
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), ".dedup-*")
+	if err != nil {
+		return fmt.Errorf("compact dedup file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	for id, t := range d.seen {
+		fmt.Fprintf(w, "%s\t%d\n", id, t.Unix())
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact dedup file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compact dedup file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("compact dedup file: %w", err)
+	}
+	return nil
+}
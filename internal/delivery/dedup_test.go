@@ -0,0 +1,140 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedup_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+
+	d, err := NewDedup(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Seen("wamid.1") {
+		t.Fatal("first sighting reported as duplicate")
+	}
+	if !d.Seen("wamid.1") {
+		t.Fatal("second sighting not reported as duplicate")
+	}
+
+	// A fresh store on the same file still knows the ID.
+	d2, err := NewDedup(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d2.Seen("wamid.1") {
+		t.Error("ID forgotten after restart")
+	}
+	if d2.Seen("wamid.2") {
+		t.Error("unknown ID reported as duplicate")
+	}
+}
+
+func TestDedup_ExpiresPerEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+	now := time.Unix(1700000000, 0)
+
+	d, err := NewDedup(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = func() time.Time { return now }
+
+	d.Seen("old")
+	now = now.Add(50 * time.Minute)
+	d.Seen("new")
+	now = now.Add(20 * time.Minute) // "old" is 70m old, "new" 20m
+
+	n, err := d.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || d.Len() != 1 {
+		t.Fatalf("Prune removed %d, %d left; want 1 and 1", n, d.Len())
+	}
+	if !d.Seen("new") {
+		t.Error("entry inside the window was dropped")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "old\t") {
+		t.Errorf("expired ID still in file after compaction: %q", data)
+	}
+	if d.Seen("old") {
+		t.Error("expired ID still deduplicated")
+	}
+}
+
+func TestDedup_LoadSkipsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+	stale := time.Now().Add(-2 * time.Hour).Unix()
+	fresh := time.Now().Unix()
+	content := fmt.Sprintf("stale\t%d\nfresh\t%d\ngarbage line\n", stale, fresh)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewDedup(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 1 {
+		t.Fatalf("loaded %d IDs, want 1", d.Len())
+	}
+	if d.Seen("stale") {
+		t.Error("expired ID still deduplicated")
+	}
+	if !d.Seen("fresh") {
+		t.Error("fresh ID not loaded")
+	}
+}
+
+// stubSource emits a fixed list of events and returns.
+type stubSource []Event
+
+func (s stubSource) Run(_ context.Context, out chan<- Event) error {
+	for _, e := range s {
+		out <- e
+	}
+	return nil
+}
+
+func TestMerge_DedupAcrossSourcesAndRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup")
+
+	run := func(sources ...Source) []string {
+		d, err := NewDedup(path, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &Merge{Sources: sources, Dedup: d}
+		out := make(chan Event, 16)
+		_ = m.Run(context.Background(), out)
+		var ids []string
+		for e := range out {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	got := run(stubSource{{ID: "a"}, {ID: "b"}}, stubSource{{ID: "a"}})
+	if len(got) != 2 {
+		t.Fatalf("first run forwarded %v, want a and b once", got)
+	}
+
+	// After a "restart", a redelivery of b is dropped; c is new.
+	got = run(stubSource{{ID: "b"}, {ID: "c"}})
+	if len(got) != 1 || got[0] != "c" {
+		t.Fatalf("second run forwarded %v, want [c]", got)
+	}
+}
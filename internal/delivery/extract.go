@@ -7,87 +7,187 @@ import (
 	"strings"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/media"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/outbound"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/transcribe"
 )
 
+// Extractor converts inbound messages into gateway-ready text. It bundles the
+// dependencies and options shared by every delivery source.
+type Extractor struct {
+	Client           *kapso.Client          // nil = no notices to senders or audio downloads (webhook replay dry runs)
+	Transcriber      transcribe.Transcriber // nil = transcription disabled
+	MaxAudioSize     int64
+	ForwardReactions bool            // false = reactions are dropped silently
+	Media            *media.Store    // nil = forward Kapso media URLs instead of local files
+	Outbound         *outbound.Queue // paces notices to senders; nil = unpaced
+}
+
 // ExtractText converts an inbound message of any supported type into a text
+// representation suitable for forwarding to the gateway. It is shorthand for
+// an Extractor that forwards reactions; see Extractor.Extract.
+func ExtractText(msg kapso.Message, client *kapso.Client, tr transcribe.Transcriber, maxAudioSize int64) (string, bool) {
+	x := &Extractor{
+		Client:           client,
+		Transcriber:      tr,
+		MaxAudioSize:     maxAudioSize,
+		ForwardReactions: true,
+	}
+	return x.Extract(msg)
+}
+
+// Extract converts an inbound message of any supported type into a text
 // representation suitable for forwarding to the gateway. It returns the text
 // and true on success, or ("", false) if the message should be skipped.
 // Unsupported types are logged and trigger a WhatsApp reply to the sender.
+// It is the Text of Event.
+func (x *Extractor) Extract(msg kapso.Message) (string, bool) {
+	evt, ok := x.Event(msg)
+	return evt.Text, ok
+}
+
+// Event converts an inbound message into an Event with both the text
+// rendering and the typed fields (type, timestamp, attachments, location,
+// reply context) filled in. Sources add the contact name and receiving
+// number. It returns false if the message should be skipped; see Extract.
 //
 // Audio transcription priority:
 //  1. Server-side transcript from Kapso (msg.Kapso.Transcript.Text)
-//  2. Local transcription via tr (download from msg.Kapso.MediaURL)
+//  2. Local transcription via x.Transcriber (download from msg.Kapso.MediaURL)
 //  3. Fallback to "[audio] (mime)" format
-func ExtractText(msg kapso.Message, client *kapso.Client, tr transcribe.Transcriber, maxAudioSize int64) (string, bool) {
+func (x *Extractor) Event(msg kapso.Message) (Event, bool) {
+	evt := Event{
+		ID:        msg.ID,
+		From:      msg.From,
+		Type:      msg.Type,
+		Timestamp: kapso.ParseTimestamp(msg.Timestamp),
+	}
+	if msg.Context != nil {
+		evt.ReplyTo = msg.Context.ID
+	}
+
 	switch msg.Type {
 	case "text":
 		if msg.Text == nil {
-			return "", false
+			return Event{}, false
 		}
-		return msg.Text.Body, true
+		evt.Text = msg.Text.Body
 
 	case "image":
 		if msg.Image == nil {
-			return "", false
+			return Event{}, false
 		}
-		return formatMediaMessage("image", msg.Image.Caption, msg.Image.MimeType, msg.Kapso), true
+		text, att := x.formatMedia(msg, "image", msg.Image.Caption, msg.Image.MimeType)
+		att.Caption = msg.Image.Caption
+		evt.Text, evt.Attachments = text, []Attachment{att}
 
 	case "document":
 		if msg.Document == nil {
-			return "", false
+			return Event{}, false
 		}
 		label := msg.Document.Filename
 		if label == "" {
 			label = msg.Document.Caption
 		}
-		return formatMediaMessage("document", label, msg.Document.MimeType, msg.Kapso), true
+		text, att := x.formatMedia(msg, "document", label, msg.Document.MimeType)
+		att.Caption, att.Filename = msg.Document.Caption, msg.Document.Filename
+		evt.Text, evt.Attachments = text, []Attachment{att}
 
 	case "audio":
 		if msg.Audio == nil {
-			return "", false
-		}
-		// 1. Use server-side transcript from Kapso if available.
-		if msg.Kapso != nil && msg.Kapso.Transcript != nil && msg.Kapso.Transcript.Text != "" {
-			return "[voice] " + msg.Kapso.Transcript.Text, true
-		}
-		// 2. Local transcription via configured transcriber.
-		if tr != nil {
-			mediaURL := kapsoMediaURL(msg.Kapso)
-			if mediaURL != "" {
-				if audio, err := client.DownloadMedia(mediaURL, maxAudioSize); err == nil {
-					if text, err := tr.Transcribe(context.Background(), audio, msg.Audio.MimeType); err == nil {
-						return "[voice] " + text, true
-					} else {
-						log.Printf("WARN: transcription failed for message %s: %v", msg.ID, err)
-					}
-				} else {
-					log.Printf("WARN: audio download failed for message %s: %v", msg.ID, err)
-				}
-			} else {
-				log.Printf("WARN: no media URL available for audio message %s", msg.ID)
-			}
+			return Event{}, false
 		}
-		// 3. Fallback.
-		return formatMediaMessage("audio", "", msg.Audio.MimeType, msg.Kapso), true
+		evt.Text, evt.Attachments = x.formatAudio(msg)
 
 	case "video":
 		if msg.Video == nil {
-			return "", false
+			return Event{}, false
 		}
-		return formatMediaMessage("video", msg.Video.Caption, msg.Video.MimeType, msg.Kapso), true
+		text, att := x.formatMedia(msg, "video", msg.Video.Caption, msg.Video.MimeType)
+		att.Caption = msg.Video.Caption
+		evt.Text, evt.Attachments = text, []Attachment{att}
 
 	case "location":
 		if msg.Location == nil {
-			return "", false
+			return Event{}, false
+		}
+		evt.Text = formatLocationMessage(msg.Location)
+		evt.Location = &Location{
+			Latitude:  msg.Location.Latitude,
+			Longitude: msg.Location.Longitude,
+			Name:      msg.Location.Name,
+			Address:   msg.Location.Address,
+		}
+
+	case "contacts":
+		if len(msg.Contacts) == 0 {
+			return Event{}, false
+		}
+		evt.Text = formatContacts(msg.Contacts)
+
+	case "interactive":
+		if msg.Interactive == nil {
+			return Event{}, false
+		}
+		text, ok := formatInteractiveReply(msg.Interactive)
+		if !ok {
+			log.Printf("unsupported interactive type %q from %s (id=%s)", msg.Interactive.Type, msg.From, msg.ID)
+			return Event{}, false
 		}
-		return formatLocationMessage(msg.Location), true
+		evt.Text = text
+
+	case "button":
+		if msg.Button == nil {
+			return Event{}, false
+		}
+		evt.Text = formatChoice("[button]", msg.Button.Text, "", "payload", msg.Button.Payload)
+
+	case "reaction":
+		// Removed reactions (empty emoji) are never forwarded.
+		if msg.Reaction == nil || msg.Reaction.Emoji == "" || !x.ForwardReactions {
+			return Event{}, false
+		}
+		evt.Text = formatReaction(msg.Reaction)
 
 	default:
 		log.Printf("unsupported message type %q from %s (id=%s)", msg.Type, msg.From, msg.ID)
-		go notifyUnsupported(msg.From, msg.Type, client)
-		return "", false
+		if x.Client != nil {
+			go x.notifyUnsupported(msg.From, msg.Type)
+		}
+		return Event{}, false
 	}
+	return evt, true
+}
+
+// formatAudio renders a voice note as its transcript when one is available,
+// and as a media attachment otherwise.
+func (x *Extractor) formatAudio(msg kapso.Message) (string, []Attachment) {
+	// 1. Use server-side transcript from Kapso if available.
+	if msg.Kapso != nil && msg.Kapso.Transcript != nil && msg.Kapso.Transcript.Text != "" {
+		att := Attachment{Kind: "audio", MimeType: msg.Audio.MimeType, URL: kapsoMediaURL(msg.Kapso)}
+		return "[voice] " + msg.Kapso.Transcript.Text, []Attachment{att}
+	}
+	// 2. Local transcription via configured transcriber.
+	if x.Transcriber != nil && x.Client != nil {
+		mediaURL := kapsoMediaURL(msg.Kapso)
+		if mediaURL != "" {
+			if audio, err := x.Client.DownloadMedia(mediaURL, x.MaxAudioSize); err == nil {
+				if text, err := x.Transcriber.Transcribe(context.Background(), audio, msg.Audio.MimeType); err == nil {
+					att := Attachment{Kind: "audio", MimeType: msg.Audio.MimeType, URL: mediaURL}
+					return "[voice] " + text, []Attachment{att}
+				} else {
+					log.Printf("WARN: transcription failed for message %s: %v", msg.ID, err)
+				}
+			} else {
+				log.Printf("WARN: audio download failed for message %s: %v", msg.ID, err)
+			}
+		} else {
+			log.Printf("WARN: no media URL available for audio message %s", msg.ID)
+		}
+	}
+	// 3. Fallback.
+	text, att := x.formatMedia(msg, "audio", "", msg.Audio.MimeType)
+	return text, []Attachment{att}
 }
 
 // kapsoMediaURL returns the media URL from KapsoMeta, or "" if unavailable.
@@ -98,6 +198,26 @@ func kapsoMediaURL(k *kapso.KapsoMeta) string {
 	return k.MediaURL
 }
 
+// formatMedia renders a media attachment and describes it as an Attachment.
+// With a media store, the attachment is downloaded and referenced as a
+// file:// URL the agent can open; if that fails, the Kapso URL is forwarded
+// as before.
+func (x *Extractor) formatMedia(msg kapso.Message, kind, label, mimeType string) (string, Attachment) {
+	k := msg.Kapso
+	if url := kapsoMediaURL(k); url != "" && x.Media != nil {
+		path, err := x.Media.Save(context.Background(), url, mimeType)
+		if err != nil {
+			log.Printf("WARN: media store failed for message %s: %v", msg.ID, err)
+		} else {
+			local := *k
+			local.MediaURL = media.FileURL(path)
+			k = &local
+		}
+	}
+	att := Attachment{Kind: kind, MimeType: mimeType, URL: kapsoMediaURL(k)}
+	return formatMediaMessage(kind, label, mimeType, k), att
+}
+
 // formatMediaMessage builds a text representation for a media attachment.
 // It uses the media URL from Kapso enrichment when available.
 func formatMediaMessage(kind, label, mimeType string, k *kapso.KapsoMeta) string {
@@ -131,15 +251,97 @@ func formatLocationMessage(loc *kapso.LocationContent) string {
 	return strings.Join(parts, " ")
 }
 
+// formatContacts renders shared contact cards, one per line, as
+// "[contact] Name (Company), +phone, email".
+func formatContacts(cards []kapso.ContactCard) string {
+	lines := make([]string, 0, len(cards))
+	for _, c := range cards {
+		name := c.DisplayName()
+		if name == "" {
+			name = "(no name)"
+		}
+		if c.Org != nil && c.Org.Company != "" {
+			name += " (" + c.Org.Company + ")"
+		}
+		fields := []string{"[contact] " + name}
+		for _, p := range c.Phones {
+			switch {
+			case p.Phone != "":
+				fields = append(fields, p.Phone)
+			case p.WaID != "":
+				fields = append(fields, "+"+p.WaID)
+			}
+		}
+		for _, e := range c.Emails {
+			if e.Email != "" {
+				fields = append(fields, e.Email)
+			}
+		}
+		lines = append(lines, strings.Join(fields, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatInteractiveReply builds a text representation for a tap on a reply
+// button or a selection from a list message. It reports false for
+// interactive subtypes it does not understand (e.g. flow replies).
+func formatInteractiveReply(in *kapso.InteractiveContent) (string, bool) {
+	switch in.Type {
+	case "button_reply":
+		if in.ButtonReply == nil {
+			return "", false
+		}
+		return formatChoice("[button]", in.ButtonReply.Title, "", "id", in.ButtonReply.ID), true
+	case "list_reply":
+		if in.ListReply == nil {
+			return "", false
+		}
+		return formatChoice("[list]", in.ListReply.Title, in.ListReply.Description, "id", in.ListReply.ID), true
+	default:
+		return "", false
+	}
+}
+
+// formatReaction builds a text representation for an emoji reaction,
+// including the ID of the message it targets.
+func formatReaction(r *kapso.ReactionContent) string {
+	return fmt.Sprintf("[reaction %s to %s]", r.Emoji, r.MessageID)
+}
+
+// formatChoice renders "<tag> title — description (key: value)", omitting
+// empty parts. The ID lets the agent match the choice it offered.
+func formatChoice(tag, title, description, key, value string) string {
+	parts := []string{tag}
+	if title != "" {
+		parts = append(parts, title)
+	}
+	if description != "" {
+		parts = append(parts, "— "+description)
+	}
+	if value != "" {
+		parts = append(parts, "("+key+": "+value+")")
+	}
+	return strings.Join(parts, " ")
+}
+
 // notifyUnsupported sends a WhatsApp reply informing the user that their
-// message type is not yet supported.
-func notifyUnsupported(from, msgType string, client *kapso.Client) {
+// message type is not yet supported. It goes through the outbound queue like
+// every other message the bridge sends.
+func (x *Extractor) notifyUnsupported(from, msgType string) {
 	to := from
 	if !strings.HasPrefix(to, "+") {
 		to = "+" + to
 	}
 	reply := fmt.Sprintf("Sorry, I can't process %s messages yet. Please send text instead.", msgType)
-	if _, err := client.SendText(to, reply); err != nil {
+	q := x.Outbound
+	if q == nil {
+		q = &outbound.Queue{}
+	}
+	err := q.Send(context.Background(), to, func(ctx context.Context) error {
+		_, err := x.Client.SendTextContext(ctx, to, reply)
+		return err
+	})
+	if err != nil {
 		log.Printf("failed to send unsupported-type notice to %s: %v", to, err)
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/media"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/transcribe"
 )
 
@@ -216,6 +217,107 @@ func TestExtractText_Location(t *testing.T) {
 	}
 }
 
+func TestExtractText_Interactive(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  kapso.Message
+		want string
+	}{
+		{
+			name: "button_reply",
+			msg: kapso.Message{
+				Type: "interactive",
+				Interactive: &kapso.InteractiveContent{
+					Type:        "button_reply",
+					ButtonReply: &kapso.ButtonReply{ID: "opt_yes", Title: "Yes"},
+				},
+			},
+			want: "[button] Yes (id: opt_yes)",
+		},
+		{
+			name: "list_reply",
+			msg: kapso.Message{
+				Type: "interactive",
+				Interactive: &kapso.InteractiveContent{
+					Type:      "list_reply",
+					ListReply: &kapso.ListRow{ID: "m9", Title: "9:00", Description: "Front desk"},
+				},
+			},
+			want: "[list] 9:00 — Front desk (id: m9)",
+		},
+		{
+			name: "template quick reply",
+			msg: kapso.Message{
+				Type:   "button",
+				Button: &kapso.ButtonContent{Text: "Confirm", Payload: "CONFIRM_42"},
+			},
+			want: "[button] Confirm (payload: CONFIRM_42)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.ID = "int-" + tt.name
+			tt.msg.From = "+1234567890"
+			text, ok := ExtractText(tt.msg, nil, nil, 0)
+			if !ok {
+				t.Fatal("expected ok=true")
+			}
+			if text != tt.want {
+				t.Errorf("got %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractText_InteractiveUnknownSubtype(t *testing.T) {
+	msg := kapso.Message{
+		ID:          "int-nfm",
+		Type:        "interactive",
+		From:        "+1234567890",
+		Interactive: &kapso.InteractiveContent{Type: "nfm_reply"},
+	}
+	if _, ok := ExtractText(msg, nil, nil, 0); ok {
+		t.Fatal("expected ok=false for unknown interactive subtype")
+	}
+}
+
+func TestExtract_Reaction(t *testing.T) {
+	msg := kapso.Message{
+		ID:       "r1",
+		Type:     "reaction",
+		From:     "+1234567890",
+		Reaction: &kapso.ReactionContent{MessageID: "wamid.reply1", Emoji: "👍"},
+	}
+
+	t.Run("forwarded when enabled", func(t *testing.T) {
+		x := &Extractor{ForwardReactions: true}
+		text, ok := x.Extract(msg)
+		if !ok {
+			t.Fatal("expected ok=true")
+		}
+		if want := "[reaction 👍 to wamid.reply1]"; text != want {
+			t.Errorf("got %q, want %q", text, want)
+		}
+	})
+
+	t.Run("dropped when disabled", func(t *testing.T) {
+		// Client is nil: a dropped reaction must not trigger the unsupported notice.
+		x := &Extractor{ForwardReactions: false}
+		if _, ok := x.Extract(msg); ok {
+			t.Fatal("expected ok=false when reactions are dropped")
+		}
+	})
+
+	t.Run("removed reaction never forwarded", func(t *testing.T) {
+		removed := msg
+		removed.Reaction = &kapso.ReactionContent{MessageID: "wamid.reply1"}
+		x := &Extractor{ForwardReactions: true}
+		if _, ok := x.Extract(removed); ok {
+			t.Fatal("expected ok=false for removed reaction")
+		}
+	})
+}
+
 func TestExtractText_UnsupportedType(t *testing.T) {
 	type capture struct {
 		to, body string
@@ -259,7 +361,7 @@ func TestExtractText_UnsupportedType(t *testing.T) {
 }
 
 func TestExtractText_NilMediaContent(t *testing.T) {
-	for _, typ := range []string{"image", "document", "audio", "video", "location"} {
+	for _, typ := range []string{"image", "document", "audio", "video", "location", "contacts", "interactive", "button", "reaction"} {
 		msg := kapso.Message{
 			ID:   "nil-" + typ,
 			Type: typ,
@@ -296,6 +398,74 @@ func TestFormatMediaMessage_WithKapsoURL(t *testing.T) {
 	}
 }
 
+func TestExtractText_ImageStoredLocally(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(png)
+	}))
+	defer srv.Close()
+
+	client := &kapso.Client{
+		APIKey:     "test-key",
+		HTTPClient: &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+	}
+	x := &Extractor{
+		Client: client,
+		Media:  &media.Store{Dir: t.TempDir(), Client: client, MaxSize: 1024},
+	}
+
+	msg := kapso.Message{
+		ID:    "m-img",
+		Type:  "image",
+		From:  "+1234567890",
+		Image: &kapso.ImageContent{Caption: "receipt", MimeType: "image/png"},
+		Kapso: &kapso.KapsoMeta{MediaURL: "https://api.kapso.ai/media/file.png"},
+	}
+	text, ok := x.Extract(msg)
+	if !ok {
+		t.Fatal("expected ok=true")
+	}
+	if !strings.Contains(text, "file://") || strings.Contains(text, "api.kapso.ai") {
+		t.Errorf("expected a local file reference, got %q", text)
+	}
+
+	// A rejected download falls back to the Kapso URL.
+	msg.Image.MimeType = "image/jpeg"
+	text, _ = x.Extract(msg)
+	if !strings.Contains(text, "https://api.kapso.ai/media/file.png") {
+		t.Errorf("expected Kapso URL fallback, got %q", text)
+	}
+}
+
+func TestExtractText_Contacts(t *testing.T) {
+	msg := kapso.Message{
+		ID:   "msg-vcard",
+		From: "15551234567",
+		Type: "contacts",
+		Contacts: []kapso.ContactCard{
+			{
+				Name:   kapso.ContactName{FormattedName: "Ana Pérez"},
+				Org:    &kapso.ContactOrg{Company: "Acme"},
+				Phones: []kapso.ContactPhone{{Phone: "+51 987 654 321", WaID: "51987654321"}},
+				Emails: []kapso.ContactEmail{{Email: "ana@example.com"}},
+			},
+			{
+				Name:   kapso.ContactName{FirstName: "Luis"},
+				Phones: []kapso.ContactPhone{{WaID: "51911111111"}},
+			},
+		},
+	}
+
+	text, ok := ExtractText(msg, nil, nil, 0)
+	if !ok {
+		t.Fatal("expected ok=true for contacts message")
+	}
+	want := "[contact] Ana Pérez (Acme), +51 987 654 321, ana@example.com\n[contact] Luis, +51911111111"
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
 func TestFormatLocationMessage(t *testing.T) {
 	loc := &kapso.LocationContent{
 		Latitude:  40.714268,
@@ -385,6 +555,7 @@ func TestExtractText_AudioLocalTranscription(t *testing.T) {
 		name        string
 		transcriber transcribe.Transcriber
 		kapso       *kapso.KapsoMeta
+		noClient    bool // e.g. a webhook replay dry run
 		wantPrefix  string
 	}{
 		{
@@ -414,6 +585,16 @@ func TestExtractText_AudioLocalTranscription(t *testing.T) {
 			},
 			wantPrefix: "[audio]",
 		},
+		{
+			name:        "nil_client_falls_back",
+			transcriber: &mockTranscriber{text: "hello", err: nil},
+			kapso: &kapso.KapsoMeta{
+				HasMedia: true,
+				MediaURL: "https://api.kapso.ai/media/voice.ogg",
+			},
+			noClient:   true,
+			wantPrefix: "[audio]",
+		},
 		{
 			name:        "no_media_url_falls_back",
 			transcriber: &mockTranscriber{text: "hello", err: nil},
@@ -435,7 +616,11 @@ func TestExtractText_AudioLocalTranscription(t *testing.T) {
 				Kapso: tc.kapso,
 			}
 
-			text, ok := ExtractText(msg, client, tc.transcriber, 1024*1024)
+			c := client
+			if tc.noClient {
+				c = nil
+			}
+			text, ok := ExtractText(msg, c, tc.transcriber, 1024*1024)
 			if !ok {
 				t.Fatal("expected ok=true")
 			}
@@ -474,3 +659,47 @@ func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	req.URL.Host = strings.TrimPrefix(t.base, "http://")
 	return t.wrapped.RoundTrip(req)
 }
+
+func TestEvent_TypedFields(t *testing.T) {
+	x := &Extractor{}
+
+	img := kapso.Message{
+		ID:        "m1",
+		From:      "+1234567890",
+		Type:      "image",
+		Timestamp: "1700000000",
+		Context:   &kapso.MessageContext{ID: "wamid.quoted"},
+		Image:     &kapso.ImageContent{MimeType: "image/jpeg", Caption: "receipt"},
+		Kapso:     &kapso.KapsoMeta{MediaURL: "https://api.kapso.ai/media/m1"},
+	}
+	evt, ok := x.Event(img)
+	if !ok {
+		t.Fatal("expected ok=true for image message")
+	}
+	if evt.Type != "image" || evt.ReplyTo != "wamid.quoted" || evt.Timestamp.Unix() != 1700000000 {
+		t.Errorf("unexpected event: %+v", evt)
+	}
+	want := Attachment{Kind: "image", MimeType: "image/jpeg", URL: "https://api.kapso.ai/media/m1", Caption: "receipt"}
+	if len(evt.Attachments) != 1 || evt.Attachments[0] != want {
+		t.Errorf("Attachments = %+v, want [%+v]", evt.Attachments, want)
+	}
+	if !strings.HasPrefix(evt.Text, "[image] receipt") {
+		t.Errorf("text fallback = %q", evt.Text)
+	}
+
+	loc := kapso.Message{
+		ID:       "m2",
+		Type:     "location",
+		Location: &kapso.LocationContent{Latitude: -12.5, Longitude: -77.25, Name: "Lima"},
+	}
+	evt, ok = x.Event(loc)
+	if !ok || evt.Location == nil {
+		t.Fatalf("expected a location, got %+v", evt)
+	}
+	if evt.Location.Latitude != -12.5 || evt.Location.Longitude != -77.25 || evt.Location.Name != "Lima" {
+		t.Errorf("Location = %+v", evt.Location)
+	}
+	if len(evt.Attachments) != 0 || !evt.Timestamp.IsZero() {
+		t.Errorf("unexpected fields on location event: %+v", evt)
+	}
+}
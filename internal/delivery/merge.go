@@ -10,7 +10,19 @@ import (
 // Merge fans in multiple Sources with message-ID deduplication.
 type Merge struct {
 	Sources []Source
-	seen    sync.Map
+	Dedup   *Dedup // nil = in-memory dedup with DefaultDedupTTL
+
+	once sync.Once
+}
+
+// dedup returns m.Dedup, creating an in-memory one on first use.
+func (m *Merge) dedup() *Dedup {
+	m.once.Do(func() {
+		if m.Dedup == nil {
+			m.Dedup, _ = NewDedup("", DefaultDedupTTL)
+		}
+	})
+	return m.Dedup
 }
 
 // Run starts all sources concurrently, deduplicates by Event.ID, and forwards
@@ -36,7 +48,7 @@ func (m *Merge) Run(ctx context.Context, out chan<- Event) error {
 	}()
 
 	for evt := range ch {
-		if _, loaded := m.seen.LoadOrStore(evt.ID, struct{}{}); loaded {
+		if m.dedup().Seen(evt.ID) {
 			log.Printf("merge: skipping duplicate message %s", evt.ID)
 			continue
 		}
@@ -47,7 +59,8 @@ func (m *Merge) Run(ctx context.Context, out chan<- Event) error {
 	return nil
 }
 
-// StartCleanup periodically clears the dedup set to bound memory usage.
+// StartCleanup periodically forgets message IDs older than the dedup TTL to
+// bound memory and file size. IDs inside the window are kept.
 func (m *Merge) StartCleanup(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -57,10 +70,9 @@ func (m *Merge) StartCleanup(ctx context.Context, interval time.Duration) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			m.seen.Range(func(key, _ interface{}) bool {
-				m.seen.Delete(key)
-				return true
-			})
+			if _, err := m.dedup().Prune(); err != nil {
+				log.Printf("merge: dedup cleanup failed: %v", err)
+			}
 		}
 	}
 }
@@ -5,23 +5,20 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
-	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/transcribe"
 )
 
 // Poller implements delivery.Source by polling the Kapso list-messages API.
 type Poller struct {
-	Client       *kapso.Client
-	Interval     time.Duration
-	StateDir     string
-	StateFile    string
-	Transcriber  transcribe.Transcriber // nil = transcription disabled
-	MaxAudioSize int64
+	Client    *kapso.Client
+	Interval  time.Duration
+	StateDir  string
+	StateFile string
+	Extractor *delivery.Extractor
 }
 
 // Run polls the Kapso API on a ticker and emits events for each new inbound
@@ -39,7 +36,7 @@ func (p *Poller) Run(ctx context.Context, out chan<- delivery.Event) error {
 	}
 
 	// Poll immediately, then on interval.
-	p.poll(&lastPoll, out)
+	p.poll(ctx, &lastPoll, out)
 
 	ticker := time.NewTicker(p.Interval)
 	defer ticker.Stop()
@@ -49,55 +46,51 @@ func (p *Poller) Run(ctx context.Context, out chan<- delivery.Event) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			p.poll(&lastPoll, out)
+			p.poll(ctx, &lastPoll, out)
 		}
 	}
 }
 
-func (p *Poller) poll(lastPoll *time.Time, out chan<- delivery.Event) {
+// poll drains every page of inbound messages since *lastPoll. The saved
+// timestamp only advances once all pages were read, so a burst larger than
+// one page is never skipped; on error the next poll starts over and Merge
+// drops the messages that were already forwarded.
+func (p *Poller) poll(ctx context.Context, lastPoll *time.Time, out chan<- delivery.Event) {
 	since := lastPoll.Format(time.RFC3339)
 
-	resp, err := p.Client.ListMessages(kapso.ListMessagesParams{
+	it := p.Client.ListAllMessages(ctx, kapso.ListMessagesParams{
 		Direction: "inbound",
 		Since:     since,
 		Limit:     100,
 	})
-	if err != nil {
-		log.Printf("poll error: %v", err)
-		return
-	}
-
-	if len(resp.Data) == 0 {
-		return
-	}
 
 	var newest time.Time
 	forwarded := 0
 
-	for _, msg := range resp.Data {
+	for it.Next() {
+		msg := it.Message()
+
 		// Track timestamp for ALL messages so the cursor advances past
-		// unsupported types (stickers, contacts, etc.) and they are not
+		// unsupported types (stickers, etc.) and they are not
 		// re-fetched on the next poll cycle.
-		msgTime := parseTimestamp(msg.Timestamp)
+		msgTime := kapso.ParseTimestamp(msg.Timestamp)
 		if !msgTime.IsZero() && msgTime.After(newest) {
 			newest = msgTime
 		}
 
-		text, ok := delivery.ExtractText(msg.Message, p.Client, p.Transcriber, p.MaxAudioSize)
+		evt, ok := p.Extractor.Event(msg.Message)
 		if !ok {
 			continue
 		}
-
-		name := ""
 		if msg.Kapso != nil {
-			name = msg.Kapso.ContactName
+			evt.Name = msg.Kapso.ContactName
 		}
+		evt.PhoneNumberID = p.Client.PhoneNumberID
 
-		out <- delivery.Event{
-			ID:   msg.ID,
-			From: msg.From,
-			Name: name,
-			Text: text,
+		select {
+		case out <- evt:
+		case <-ctx.Done():
+			return
 		}
 		forwarded++
 	}
@@ -106,22 +99,17 @@ func (p *Poller) poll(lastPoll *time.Time, out chan<- delivery.Event) {
 		log.Printf("forwarded %d message(s)", forwarded)
 	}
 
+	if err := it.Err(); err != nil {
+		log.Printf("poll error (page %d): %v", it.Pages()+1, err)
+		return
+	}
+
 	if !newest.IsZero() {
 		*lastPoll = newest.Add(time.Second)
 		saveState(p.StateFile, *lastPoll)
 	}
 }
 
-func parseTimestamp(s string) time.Time {
-	if t, err := time.Parse(time.RFC3339, s); err == nil {
-		return t
-	}
-	if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
-		return time.Unix(n, 0).UTC()
-	}
-	return time.Time{}
-}
-
 func loadState(path string) time.Time {
 	data, err := os.ReadFile(path)
 	if err != nil {
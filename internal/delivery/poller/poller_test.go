@@ -0,0 +1,117 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+)
+
+// rewriteTransport rewrites all request URLs to point at the test server.
+type rewriteTransport struct {
+	base    string
+	wrapped http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(t.base, "http://")
+	return t.wrapped.RoundTrip(req)
+}
+
+// newTestPoller serves `pages` pages of two messages each; page failAt (if
+// > 0) answers with a server error.
+func newTestPoller(t *testing.T, pages, failAt int) (*Poller, func()) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 1
+		if after := r.URL.Query().Get("after"); after != "" {
+			_, _ = fmt.Sscanf(after, "p%d", &page)
+		}
+		if page == failAt {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		next := ""
+		if page < pages {
+			next = fmt.Sprintf("p%d", page+1)
+		}
+		var msgs []string
+		for i := 0; i < 2; i++ {
+			n := (page-1)*2 + i
+			msgs = append(msgs, fmt.Sprintf(`{"id":"wamid.%d","from":"15551234567","timestamp":"%d","type":"text","text":{"body":"msg %d"}}`,
+				n, 1700000000+n, n))
+		}
+		_, _ = fmt.Fprintf(w, `{"data":[%s],"paging":{"cursors":{"after":%q}}}`, strings.Join(msgs, ","), next)
+	}))
+
+	dir := t.TempDir()
+	p := &Poller{
+		Client: &kapso.Client{
+			APIKey:        "test-key",
+			PhoneNumberID: "12345",
+			HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		},
+		Interval:  time.Minute,
+		StateDir:  dir,
+		StateFile: filepath.Join(dir, "last-poll"),
+		Extractor: &delivery.Extractor{},
+	}
+	return p, srv.Close
+}
+
+func TestPoll_DrainsAllPages(t *testing.T) {
+	p, done := newTestPoller(t, 3, 0)
+	defer done()
+
+	start := time.Unix(1600000000, 0).UTC()
+	lastPoll := start
+	out := make(chan delivery.Event, 10)
+
+	p.poll(context.Background(), &lastPoll, out)
+
+	if len(out) != 6 {
+		t.Fatalf("got %d events, want 6 (all three pages)", len(out))
+	}
+	for i := 0; i < 6; i++ {
+		if evt := <-out; evt.ID != fmt.Sprintf("wamid.%d", i) {
+			t.Errorf("event %d ID = %q", i, evt.ID)
+		}
+	}
+
+	want := time.Unix(1700000005, 0).Add(time.Second).UTC()
+	if !lastPoll.Equal(want) {
+		t.Errorf("lastPoll = %v, want %v", lastPoll, want)
+	}
+	if saved := loadState(p.StateFile); !saved.Equal(want) {
+		t.Errorf("saved state = %v, want %v", saved, want)
+	}
+}
+
+func TestPoll_DoesNotAdvanceOnPageError(t *testing.T) {
+	p, done := newTestPoller(t, 3, 2)
+	defer done()
+
+	start := time.Unix(1600000000, 0).UTC()
+	lastPoll := start
+	out := make(chan delivery.Event, 10)
+
+	p.poll(context.Background(), &lastPoll, out)
+
+	if len(out) != 2 {
+		t.Errorf("got %d events, want 2 from the first page", len(out))
+	}
+	if !lastPoll.Equal(start) {
+		t.Errorf("lastPoll advanced to %v despite a failed page", lastPoll)
+	}
+	if saved := loadState(p.StateFile); !saved.IsZero() {
+		t.Errorf("state saved as %v, want nothing", saved)
+	}
+}
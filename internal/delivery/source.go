@@ -2,14 +2,56 @@ package delivery
 
 import (
 	"context"
+	"time"
 )
 
-// Event represents a single inbound message ready for the gateway.
+// Event represents a single inbound message ready for the gateway. Text is
+// the flattened rendering every gateway understands; the typed fields carry
+// the same information for gateways that can use it natively.
 type Event struct {
-	ID   string // Kapso message ID (idempotency key)
-	From string // sender phone
-	Name string // contact display name
-	Text string // extracted, gateway-ready text
+	ID      string // Kapso message ID (idempotency key)
+	From    string // sender phone
+	Name    string // contact display name
+	Text    string // extracted, gateway-ready text
+	ReplyTo string // ID of the message the user quoted, if any
+
+	Type        string       // WhatsApp message type: "text", "image", "audio", "location", ...
+	Timestamp   time.Time    // when the user sent the message; zero if unknown
+	Attachments []Attachment // media sent with the message
+	Location    *Location    // shared location pin, if any
+
+	// PhoneNumberID is the business number that received the message. Empty
+	// when the source does not know it; the bridge then uses its first number.
+	PhoneNumberID string
+
+	// Parts lists the IDs of all messages coalesced into this event, oldest
+	// first (ID is the last one). Nil for a single message.
+	Parts []string
+}
+
+// Attachment is a media file sent with a message.
+type Attachment struct {
+	Kind     string // "image", "document", "audio" or "video"
+	MimeType string
+	URL      string // file:// URL when stored locally, otherwise the Kapso URL; may be empty
+	Caption  string
+	Filename string // documents only
+}
+
+// Location is a location pin shared by the user.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Name      string
+	Address   string
+}
+
+// IDs returns the IDs of every message this event stands for.
+func (e Event) IDs() []string {
+	if len(e.Parts) > 0 {
+		return e.Parts
+	}
+	return []string{e.ID}
 }
 
 // Source produces inbound message events from a delivery channel (poller, webhook, etc.).
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultArchiveMaxSize is the archive size at which it is rotated to
+// Path+".1".
+const DefaultArchiveMaxSize = 10 * 1024 * 1024
+
+// ErrRecordNotFound is returned by FindRecord for an unknown record ID.
+var ErrRecordNotFound = errors.New("webhook record not found")
+
+// Record is one archived webhook payload.
+type Record struct {
+	ID      string      `json:"id"` // content hash of Body; redeliveries share it
+	Time    time.Time   `json:"time"`
+	Format  string      `json:"format"` // "kapso-native", "meta" or "unknown"
+	Headers http.Header `json:"headers"`
+
+	// Body is the payload byte for byte (base64 in the file), so a replay
+	// still matches the original signature headers.
+	Body []byte `json:"body"`
+}
+
+// Payload returns the raw request body.
+func (r Record) Payload() []byte {
+	return r.Body
+}
+
+// Archive appends verified webhook payloads to a JSONL file at Path, so
+// parsing problems can be investigated and replayed later.
+type Archive struct {
+	Path    string
+	MaxSize int64 // rotate when the archive grows past this; 0 = DefaultArchiveMaxSize
+
+	mu sync.Mutex
+}
+
+// Add archives one payload with its request headers and detected format.
+func (a *Archive) Add(headers http.Header, format string, body []byte) (Record, error) {
+	sum := sha256.Sum256(body)
+	rec := Record{
+		ID:      hex.EncodeToString(sum[:6]),
+		Time:    time.Now().UTC(),
+		Format:  format,
+		Headers: headers,
+		Body:    body,
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, fmt.Errorf("marshal webhook record: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	maxSize := a.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultArchiveMaxSize
+	}
+	if info, err := os.Stat(a.Path); err == nil && info.Size() >= maxSize {
+		if err := os.Rename(a.Path, a.Path+".1"); err != nil {
+			return Record{}, fmt.Errorf("rotate webhook archive: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(a.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Record{}, fmt.Errorf("open webhook archive: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return Record{}, fmt.Errorf("write webhook archive: %w", err)
+	}
+	return rec, f.Close()
+}
+
+// ReadRecords returns every record in an archive file, oldest first.
+func ReadRecords(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open webhook archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var out []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: decode webhook record: %w", path, n, err)
+		}
+		out = append(out, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read webhook archive: %w", err)
+	}
+	return out, nil
+}
+
+// FindRecord returns the most recent record with id from the archive at path,
+// including its rotated predecessor.
+func FindRecord(path, id string) (Record, error) {
+	var found *Record
+	for _, p := range []string{path + ".1", path} {
+		recs, err := ReadRecords(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return Record{}, err
+		}
+		for i := range recs {
+			if recs[i].ID == id {
+				found = &recs[i]
+			}
+		}
+	}
+	if found == nil {
+		return Record{}, fmt.Errorf("%s: %w", id, ErrRecordNotFound)
+	}
+	return *found, nil
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+func TestArchive_AddAndFind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.jsonl")
+	a := &Archive{Path: path, MaxSize: 300}
+
+	headers := http.Header{"X-Webhook-Signature": []string{"abc"}}
+	first, err := a.Add(headers, "kapso-native", []byte(`{"type":"whatsapp.message.received","data":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Large enough to rotate the archive before the next write.
+	if _, err := a.Add(nil, "meta", []byte(`{"object":"whatsapp_business_account","entry":[],"pad":"`+strings.Repeat("x", 300)+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+	last, err := a.Add(nil, "unknown", []byte("not json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FindRecord(path, first.ID)
+	if err != nil {
+		t.Fatalf("FindRecord in rotated archive: %v", err)
+	}
+	if got.Format != "kapso-native" || got.Headers.Get("X-Webhook-Signature") != "abc" {
+		t.Errorf("unexpected record: %+v", got)
+	}
+
+	got, err = FindRecord(path, last.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Payload()) != "not json" {
+		t.Errorf("Payload = %q, want the original body", got.Payload())
+	}
+
+	if _, err := FindRecord(path, "000000000000"); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("unknown ID: got %v, want ErrRecordNotFound", err)
+	}
+
+	recs, err := ReadRecords(path)
+	if err != nil || len(recs) != 1 {
+		t.Errorf("current archive: %d records, %v; want only the last one after rotation", len(recs), err)
+	}
+}
+
+func TestHandleEvent_ArchivesAndReplays(t *testing.T) {
+	srv := newTestServer()
+	srv.Archive = &Archive{Path: filepath.Join(t.TempDir(), "webhooks.jsonl")}
+
+	body := `{"type":"whatsapp.message.received","data":[{"phone_number_id":"pn1",
+		"message":{"id":"wamid.in1","from":"5511999999999","type":"text","text":{"body":"hi"}}}]}`
+	out := make(chan delivery.Event, 2)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	srv.handleEvent(httptest.NewRecorder(), req, out)
+	live := <-out
+
+	recs, err := ReadRecords(srv.Archive.Path)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("archive: %d records, %v", len(recs), err)
+	}
+	if recs[0].Format != "kapso-native" {
+		t.Errorf("Format = %q", recs[0].Format)
+	}
+
+	if err := srv.Replay(recs[0], out); err != nil {
+		t.Fatal(err)
+	}
+	if replayed := <-out; replayed.ID != live.ID || replayed.Text != live.Text || replayed.PhoneNumberID != live.PhoneNumberID {
+		t.Errorf("replayed event %+v differs from live %+v", replayed, live)
+	}
+
+	if err := srv.Replay(Record{ID: "x", Body: []byte(`{}`)}, out); err == nil {
+		t.Error("expected an error for an unrecognized payload")
+	}
+}
+
+func TestArchive_KeepsSignedBytes(t *testing.T) {
+	const secret = "webhook-secret"
+	body := `{"type": "whatsapp.message.received", "data": [{"message": {"id": "wamid.sig", "from": "5511999999999", "type": "text",
+		"text": {"body": "a < b & c > d"}}}]}`
+	srv := newTestServer()
+	srv.AppSecret = secret
+	srv.Archive = &Archive{Path: filepath.Join(t.TempDir(), "webhooks.jsonl")}
+
+	out := make(chan delivery.Event, 2)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("X-Webhook-Signature", computeHMAC(body, secret))
+	srv.handleEvent(httptest.NewRecorder(), req, out)
+	<-out
+
+	recs, err := ReadRecords(srv.Archive.Path)
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("archive: %d records, %v", len(recs), err)
+	}
+	if string(recs[0].Payload()) != body {
+		t.Errorf("Payload = %q, want the body byte for byte", recs[0].Payload())
+	}
+
+	// Replayed with its original headers, the record passes the signature check.
+	replay := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(recs[0].Payload())))
+	replay.Header.Set("X-Webhook-Signature", recs[0].Headers.Get("X-Webhook-Signature"))
+	w := httptest.NewRecorder()
+	srv.handleEvent(w, replay, out)
+	if w.Code != http.StatusOK {
+		t.Fatalf("replay status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if evt := <-out; evt.Text != "a < b & c > d" {
+		t.Errorf("replayed text = %q", evt.Text)
+	}
+}
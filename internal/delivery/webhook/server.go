@@ -12,32 +12,84 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
-	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/transcribe"
 )
 
 // Server is an HTTP webhook receiver that implements delivery.Source.
 // It receives both Kapso-native and Meta-format WhatsApp webhook events
 // and emits delivery.Event for ALL message types (text, image, document,
-// audio, video, location).
+// audio, video, location, contacts, interactive replies, reactions).
 type Server struct {
-	Addr         string
-	VerifyToken  string
-	AppSecret    string
-	Client       *kapso.Client
-	Transcriber  transcribe.Transcriber // nil = transcription disabled
-	MaxAudioSize int64
+	Addr        string
+	VerifyToken string
+	AppSecret   string
+	Extractor   *delivery.Extractor
+
+	// Extractors, if set, maps the receiving phone number ID to the extractor
+	// for that number, so media is fetched with the right API key. Payloads
+	// for other numbers use Extractor.
+	Extractors map[string]*delivery.Extractor
+
+	// Health, if set, adds bridge metrics (e.g. outbound queue depth) to the
+	// /health response, which then becomes a JSON object.
+	Health func() map[string]interface{}
+
+	// OnStatus, if set, receives delivery statuses (sent, delivered, read,
+	// failed) of outbound messages, with the ID of the phone number that sent
+	// them. Without it statuses are ignored.
+	OnStatus func(phoneNumberID string, st kapso.Status)
+
+	// Workers process accepted payloads (extraction, media download,
+	// transcription) outside the HTTP handler. At most QueueSize payloads
+	// wait; beyond that the server answers 503 so Kapso redelivers later.
+	Workers   int // default DefaultWorkers
+	QueueSize int // default DefaultQueueSize
+
+	// Archive, if set, keeps every payload that passed signature validation
+	// and was accepted, for debugging and `kapso-whatsapp-cli webhook replay`.
+	// Payloads answered with 503 are archived when they are redelivered.
+	Archive *Archive
+
+	jobs   chan job // nil until Run starts the workers
+	mu     sync.RWMutex
+	closed bool // jobs is closed; guarded by mu
+}
+
+// Worker pool defaults.
+const (
+	DefaultWorkers   = 4
+	DefaultQueueSize = 100
+)
+
+// retryAfter is the Retry-After hint, in seconds, sent when the queue is full.
+const retryAfter = "5"
+
+// drainTimeout bounds how long shutdown waits for queued payloads to be
+// processed before the remaining events are dropped.
+const drainTimeout = 30 * time.Second
+
+// job is one accepted webhook payload.
+type job struct {
+	format webhookFormat
+	body   []byte
 }
 
 // Run starts the webhook HTTP server and emits events on out. It blocks until
-// ctx is cancelled, at which point the server is gracefully shut down.
+// ctx is cancelled, at which point the server is gracefully shut down: it
+// stops accepting payloads, then emits the events of every payload it has
+// already acknowledged before returning. out must be read until then.
 func (s *Server) Run(ctx context.Context, out chan<- delivery.Event) error {
+	workCtx, stopWork := context.WithCancel(context.Background())
+	defer stopWork()
+	workers := s.startWorkers(workCtx, out)
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/webhook", s.webhookHandler(out))
-	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/health", s.handleHealth)
 
 	srv := &http.Server{
 		Addr:              s.Addr,
@@ -52,19 +104,81 @@ func (s *Server) Run(ctx context.Context, out chan<- delivery.Event) error {
 	log.Printf("webhook server listening on %s", ln.Addr())
 
 	// Shut down gracefully when ctx is cancelled.
+	shutdown := make(chan struct{})
 	go func() {
 		<-ctx.Done()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = srv.Shutdown(shutdownCtx)
+		close(shutdown)
 	}()
 
 	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+		s.drain(workers, stopWork)
 		return fmt.Errorf("webhook serve: %w", err)
 	}
+	// Serve returns as soon as Shutdown starts; wait until no handler can
+	// queue another payload.
+	<-shutdown
+	s.drain(workers, stopWork)
 	return nil
 }
 
+// startWorkers creates the payload queue and the workers draining it. The
+// workers run until the queue is closed and empty; ctx is only cancelled to
+// give up on the payloads that are left.
+func (s *Server) startWorkers(ctx context.Context, out chan<- delivery.Event) *sync.WaitGroup {
+	workers, size := s.Workers, s.QueueSize
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	s.jobs = make(chan job, size)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(jobs <-chan job) {
+			defer wg.Done()
+			for j := range jobs {
+				s.process(ctx, j, out)
+			}
+		}(s.jobs)
+	}
+	log.Printf("webhook: %d workers, queue of %d", workers, size)
+	return &wg
+}
+
+// drain closes the payload queue and waits for the workers to process what
+// is left. After drainTimeout, stop is called and the remaining events are
+// dropped; the journal and Kapso's redelivery cover for them.
+func (s *Server) drain(workers *sync.WaitGroup, stop context.CancelFunc) {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.jobs)
+	}
+	left := len(s.jobs)
+	s.mu.Unlock()
+	if left > 0 {
+		log.Printf("webhook: processing %d queued payload(s) before shutdown", left)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+		log.Printf("webhook: queued payloads not processed within %s, dropping the rest", drainTimeout)
+		stop()
+		<-done
+	}
+}
+
 // webhookHandler returns an http.HandlerFunc that processes both verification
 // (GET) and event delivery (POST).
 func (s *Server) webhookHandler(out chan<- delivery.Event) http.HandlerFunc {
@@ -137,8 +251,10 @@ func formatName(f webhookFormat) string {
 	}
 }
 
-// handleEvent parses a webhook POST, detects the payload format (Kapso native
-// or Meta), and emits events for inbound messages.
+// handleEvent validates a webhook POST, detects the payload format (Kapso
+// native or Meta) and queues it for the workers. When the queue is full it
+// answers 503 with Retry-After so the payload is redelivered rather than
+// lost. Without workers (Run not called) the payload is processed inline.
 func (s *Server) handleEvent(w http.ResponseWriter, r *http.Request, out chan<- delivery.Event) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -159,21 +275,86 @@ func (s *Server) handleEvent(w http.ResponseWriter, r *http.Request, out chan<-
 	format := detectFormat(body)
 	log.Printf("webhook: detected %s format", formatName(format))
 
-	// Acknowledge immediately — process asynchronously.
-	w.WriteHeader(http.StatusOK)
+	if format == formatUnknown {
+		s.archive(r.Header, format, body)
+		w.WriteHeader(http.StatusOK)
+		log.Printf("webhook: unrecognized payload format, ignoring")
+		return
+	}
+
+	j := job{format: format, body: body}
+	if s.jobs == nil {
+		s.archive(r.Header, format, body)
+		w.WriteHeader(http.StatusOK)
+		s.process(context.Background(), j, out)
+		return
+	}
+
+	s.mu.RLock()
+	queued, closed := false, s.closed
+	if !closed {
+		select {
+		case s.jobs <- j:
+			queued = true
+		default:
+		}
+	}
+	s.mu.RUnlock()
+
+	if queued {
+		// Acknowledge immediately — a worker processes the payload.
+		s.archive(r.Header, format, body)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if closed {
+		log.Printf("webhook: shutting down, asking sender to retry")
+	} else {
+		log.Printf("webhook: queue full (%d), asking sender to retry", cap(s.jobs))
+	}
+	w.Header().Set("Retry-After", retryAfter)
+	http.Error(w, "busy", http.StatusServiceUnavailable)
+}
+
+// archive records an accepted payload, if archiving is enabled.
+func (s *Server) archive(headers http.Header, format webhookFormat, body []byte) {
+	if s.Archive == nil {
+		return
+	}
+	if rec, err := s.Archive.Add(headers, formatName(format), body); err != nil {
+		log.Printf("webhook: failed to archive payload: %v", err)
+	} else {
+		log.Printf("webhook: archived payload %s", rec.ID)
+	}
+}
 
-	switch format {
+// Replay feeds an archived payload through the same parsing path as a live
+// request: events are emitted on out, which the caller must drain, and
+// statuses go to OnStatus.
+func (s *Server) Replay(rec Record, out chan<- delivery.Event) error {
+	body := rec.Payload()
+	format := detectFormat(body)
+	if format == formatUnknown {
+		return fmt.Errorf("record %s: unrecognized payload format", rec.ID)
+	}
+	s.process(context.Background(), job{format: format, body: body}, out)
+	return nil
+}
+
+// process emits the events and statuses of one payload. Events still waiting
+// for a reader when ctx is done are dropped; Run only cancels it when
+// shutdown gives up on the queue.
+func (s *Server) process(ctx context.Context, j job, out chan<- delivery.Event) {
+	switch j.format {
 	case formatKapso:
-		s.handleKapsoPayload(body, out)
+		s.handleKapsoPayload(ctx, j.body, out)
 	case formatMeta:
-		s.handleMetaPayload(body, out)
-	default:
-		log.Printf("webhook: unrecognized payload format, ignoring")
+		s.handleMetaPayload(ctx, j.body, out)
 	}
 }
 
 // handleMetaPayload processes a Meta-format webhook payload.
-func (s *Server) handleMetaPayload(body []byte, out chan<- delivery.Event) {
+func (s *Server) handleMetaPayload(ctx context.Context, body []byte, out chan<- delivery.Event) {
 	var payload kapso.WebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		log.Printf("webhook: invalid Meta JSON: %v", err)
@@ -192,54 +373,100 @@ func (s *Server) handleMetaPayload(body []byte, out chan<- delivery.Event) {
 				contacts[c.WaID] = c.Profile.Name
 			}
 
+			phoneNumberID := change.Value.Metadata.PhoneNumberID
 			for _, msg := range change.Value.Messages {
-				s.emitMessage(msg, contacts, out)
+				s.emitMessage(ctx, phoneNumberID, msg, contacts, out)
+			}
+
+			for _, st := range change.Value.Statuses {
+				s.emitStatus(phoneNumberID, st)
 			}
 		}
 	}
 }
 
 // handleKapsoPayload processes a Kapso-native webhook payload.
-func (s *Server) handleKapsoPayload(body []byte, out chan<- delivery.Event) {
+func (s *Server) handleKapsoPayload(ctx context.Context, body []byte, out chan<- delivery.Event) {
 	var payload kapso.KapsoWebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		log.Printf("webhook: invalid Kapso JSON: %v", err)
 		return
 	}
 
-	// Only process message-received events; ignore status updates.
+	if st, ok := kapsoStatusEvents[payload.Type]; ok {
+		for _, item := range payload.Data {
+			s.emitStatus(item.PhoneNumberID, kapso.Status{
+				ID:        item.Message.ID,
+				Status:    st,
+				Timestamp: item.Message.Timestamp,
+				Errors:    item.Message.Errors,
+			})
+		}
+		return
+	}
+
 	if payload.Type != "whatsapp.message.received" {
 		log.Printf("webhook: ignoring Kapso event type %q", payload.Type)
 		return
 	}
 
 	for _, item := range payload.Data {
-		s.emitMessage(item.Message, nil, out)
+		s.emitMessage(ctx, item.PhoneNumberID, item.Message, nil, out)
+	}
+}
+
+// kapsoStatusEvents maps Kapso-native status event types to WhatsApp statuses.
+var kapsoStatusEvents = map[string]string{
+	"whatsapp.message.sent":      "sent",
+	"whatsapp.message.delivered": "delivered",
+	"whatsapp.message.read":      "read",
+	"whatsapp.message.failed":    "failed",
+}
+
+// emitStatus hands a delivery status to OnStatus.
+func (s *Server) emitStatus(phoneNumberID string, st kapso.Status) {
+	if s.OnStatus == nil || st.ID == "" {
+		return
+	}
+	if st.Status == "failed" {
+		code := 0
+		if len(st.Errors) > 0 {
+			code = st.Errors[0].Code
+		}
+		log.Printf("webhook: message %s failed to deliver (code %d)", st.ID, code)
 	}
+	s.OnStatus(phoneNumberID, st)
+}
+
+// extractor returns the extractor for the receiving phone number.
+func (s *Server) extractor(phoneNumberID string) *delivery.Extractor {
+	if x, ok := s.Extractors[phoneNumberID]; ok {
+		return x
+	}
+	return s.Extractor
 }
 
 // emitMessage extracts text from a message and emits it as a delivery.Event.
 // contacts is an optional Meta-format contact-name lookup (nil for Kapso native).
-func (s *Server) emitMessage(msg kapso.Message, contacts map[string]string, out chan<- delivery.Event) {
-	text, ok := delivery.ExtractText(msg, s.Client, s.Transcriber, s.MaxAudioSize)
+func (s *Server) emitMessage(ctx context.Context, phoneNumberID string, msg kapso.Message, contacts map[string]string, out chan<- delivery.Event) {
+	evt, ok := s.extractor(phoneNumberID).Event(msg)
 	if !ok {
 		return
 	}
 
-	name := ""
 	if msg.Kapso != nil && msg.Kapso.ContactName != "" {
-		name = msg.Kapso.ContactName
+		evt.Name = msg.Kapso.ContactName
 	} else if contacts != nil {
-		name = contacts[msg.From]
+		evt.Name = contacts[msg.From]
 	}
+	evt.PhoneNumberID = phoneNumberID
 
-	out <- delivery.Event{
-		ID:   msg.ID,
-		From: msg.From,
-		Name: name,
-		Text: text,
+	select {
+	case out <- evt:
+		log.Printf("webhook: received message %s from %s", msg.ID, msg.From)
+	case <-ctx.Done():
+		log.Printf("webhook: dropping message %s from %s: %v", msg.ID, msg.From, ctx.Err())
 	}
-	log.Printf("webhook: received message %s from %s", msg.ID, msg.From)
 }
 
 // validateSignature checks HMAC-SHA256 for either Kapso or Meta webhook format.
@@ -268,8 +495,23 @@ func hmacValid(body []byte, hexSig, secret string) bool {
 	return hmac.Equal([]byte(hexSig), []byte(expected))
 }
 
-// handleHealth returns 200 OK — used by the CLI status command.
-func handleHealth(w http.ResponseWriter, _ *http.Request) {
+// handleHealth returns 200 OK — used by the CLI status command. With a
+// Health hook it answers {"status":"ok", ...metrics} instead of plain "ok".
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	if s.Health == nil {
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "ok")
+		return
+	}
+
+	info := map[string]interface{}{"status": "ok"}
+	if s.jobs != nil {
+		info["webhook_queue_depth"] = len(s.jobs)
+	}
+	for k, v := range s.Health() {
+		info[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprint(w, "ok")
+	_ = json.NewEncoder(w).Encode(info)
 }
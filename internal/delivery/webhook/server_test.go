@@ -1,14 +1,19 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
@@ -115,9 +120,11 @@ func TestValidateSignature(t *testing.T) {
 // newTestServer creates a Server with no HMAC validation and no transcriber.
 func newTestServer() *Server {
 	return &Server{
-		Client: &kapso.Client{
-			APIKey:        "test",
-			PhoneNumberID: "12345",
+		Extractor: &delivery.Extractor{
+			Client: &kapso.Client{
+				APIKey:        "test",
+				PhoneNumberID: "12345",
+			},
 		},
 	}
 }
@@ -234,6 +241,31 @@ func TestHandleEvent_MetaFormat(t *testing.T) {
 	}
 }
 
+func TestHandleEvent_QuotedReply(t *testing.T) {
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"e1","changes":[{"field":"messages","value":{
+		"messages":[{"id":"wamid.reply","from":"5511888888888","timestamp":"1700000000","type":"text",
+		"text":{"body":"yes, that one"},"context":{"from":"15550001111","id":"wamid.quoted"}}]}}]}]}`
+
+	out := make(chan delivery.Event, 1)
+	srv := newTestServer()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.handleEvent(w, req, out)
+
+	if len(out) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(out))
+	}
+	evt := <-out
+	if evt.ReplyTo != "wamid.quoted" {
+		t.Errorf("ReplyTo = %q, want %q", evt.ReplyTo, "wamid.quoted")
+	}
+	if evt.Text != "yes, that one" {
+		t.Errorf("Text = %q, want %q", evt.Text, "yes, that one")
+	}
+}
+
 func TestHandleEvent_IgnoresNonMessageEvents(t *testing.T) {
 	payload := kapso.KapsoWebhookPayload{
 		Type: "whatsapp.message.sent",
@@ -267,6 +299,200 @@ func TestHandleEvent_IgnoresNonMessageEvents(t *testing.T) {
 	}
 }
 
+func TestHandleEvent_Statuses(t *testing.T) {
+	var got []kapso.Status
+	var numbers []string
+	srv := newTestServer()
+	srv.OnStatus = func(phoneNumberID string, st kapso.Status) {
+		numbers = append(numbers, phoneNumberID)
+		got = append(got, st)
+	}
+
+	meta := `{"object":"whatsapp_business_account","entry":[{"id":"e1","changes":[{"field":"messages","value":{
+		"metadata":{"phone_number_id":"pn1"},
+		"statuses":[{"id":"wamid.out1","status":"failed","timestamp":"1700000000","recipient_id":"5511888888888",
+		"errors":[{"code":131047,"title":"Re-engagement message","error_data":{"details":"24h window closed"}}]}]}}]}]}`
+	kapsoNative := `{"type":"whatsapp.message.delivered","data":[{"phone_number_id":"pn2","message":{"id":"wamid.out2","timestamp":"1700000001"}}]}`
+
+	out := make(chan delivery.Event, 1)
+	for _, body := range []string{meta, kapsoNative} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		srv.handleEvent(httptest.NewRecorder(), req, out)
+	}
+
+	if len(out) != 0 {
+		t.Fatalf("statuses must not emit message events, got %d", len(out))
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(got))
+	}
+	if got[0].ID != "wamid.out1" || got[0].Status != "failed" || len(got[0].Errors) != 1 || got[0].Errors[0].Code != 131047 {
+		t.Errorf("unexpected Meta status: %+v", got[0])
+	}
+	if got[0].Errors[0].ErrorData == nil || got[0].Errors[0].ErrorData.Details != "24h window closed" {
+		t.Errorf("missing error details: %+v", got[0].Errors[0])
+	}
+	if got[1].ID != "wamid.out2" || got[1].Status != "delivered" {
+		t.Errorf("unexpected Kapso status: %+v", got[1])
+	}
+	if numbers[0] != "pn1" || numbers[1] != "pn2" {
+		t.Errorf("status phone numbers = %v, want [pn1 pn2]", numbers)
+	}
+}
+
+func TestHandleEvent_RoutesByPhoneNumber(t *testing.T) {
+	srv := newTestServer()
+	srv.Extractors = map[string]*delivery.Extractor{
+		"pn2": {Client: &kapso.Client{APIKey: "second", PhoneNumberID: "pn2"}},
+	}
+
+	meta := `{"object":"whatsapp_business_account","entry":[{"id":"e1","changes":[{"field":"messages","value":{
+		"metadata":{"phone_number_id":"pn2"},
+		"messages":[{"id":"wamid.in1","from":"5511999999999","type":"text","text":{"body":"hi"}}]}}]}]}`
+	kapsoNative := `{"type":"whatsapp.message.received","data":[{"phone_number_id":"pn3",
+		"message":{"id":"wamid.in2","from":"5511999999999","type":"text","text":{"body":"hello"}}}]}`
+
+	out := make(chan delivery.Event, 2)
+	for _, body := range []string{meta, kapsoNative} {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		srv.handleEvent(httptest.NewRecorder(), req, out)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(out))
+	}
+	if evt := <-out; evt.PhoneNumberID != "pn2" {
+		t.Errorf("Meta event PhoneNumberID = %q, want pn2", evt.PhoneNumberID)
+	}
+	// Numbers without their own extractor still get through.
+	if evt := <-out; evt.PhoneNumberID != "pn3" || evt.Text != "hello" {
+		t.Errorf("Kapso event = %+v, want pn3", evt)
+	}
+}
+
+func TestHandleEvent_QueuedToWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := make(chan delivery.Event, 1)
+	srv := newTestServer()
+	srv.Workers, srv.QueueSize = 1, 1
+	srv.startWorkers(ctx, out)
+
+	body := `{"type":"whatsapp.message.received","data":[{"message":{"id":"wamid.q1","from":"5511999999999","type":"text","text":{"body":"queued"}}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.handleEvent(w, req, out)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	select {
+	case evt := <-out:
+		if evt.ID != "wamid.q1" || evt.Text != "queued" {
+			t.Errorf("unexpected event: %+v", evt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker never emitted the event")
+	}
+}
+
+func TestRun_DrainsQueueOnShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	srv := newTestServer()
+	srv.Addr = addr
+	srv.Workers, srv.QueueSize = 1, 4
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan delivery.Event) // not read until after shutdown starts
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Run(ctx, out) }()
+
+	body := `{"type":"whatsapp.message.received","data":[{"message":{"id":"wamid.d1","from":"5511999999999","type":"text","text":{"body":"late"}}}]}`
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = http.Post("http://"+addr+"/webhook", "application/json", strings.NewReader(body))
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// The payload was acknowledged, so shutting down must still emit it.
+	cancel()
+	select {
+	case evt := <-out:
+		if evt.ID != "wamid.d1" {
+			t.Errorf("emitted %+v", evt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acknowledged payload was not emitted after shutdown")
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after draining")
+	}
+}
+
+func TestHandleEvent_SaturatedReturns503(t *testing.T) {
+	srv := newTestServer()
+	srv.Archive = &Archive{Path: filepath.Join(t.TempDir(), "webhooks.jsonl")}
+	srv.jobs = make(chan job, 1) // no workers draining it
+	srv.jobs <- job{}
+
+	body := `{"type":"whatsapp.message.received","data":[]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.handleEvent(w, req, make(chan delivery.Event, 1))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+	// The redelivery is archived, not the rejected attempt.
+	if _, err := os.Stat(srv.Archive.Path); !os.IsNotExist(err) {
+		t.Errorf("rejected payload was archived (stat: %v)", err)
+	}
+}
+
+func TestProcess_StopsWhenCancelled(t *testing.T) {
+	srv := newTestServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body := `{"type":"whatsapp.message.received","data":[{"message":{"id":"wamid.c1","from":"5511999999999","type":"text","text":{"body":"hi"}}}]}`
+	done := make(chan struct{})
+	go func() {
+		srv.process(ctx, job{format: formatKapso, body: []byte(body)}, make(chan delivery.Event)) // nobody reads
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("process blocked on the event channel after cancellation")
+	}
+}
+
 func TestHandleEvent_SignatureRejection(t *testing.T) {
 	payload := `{"type":"whatsapp.message.received","data":[]}`
 
@@ -335,6 +561,35 @@ func TestHandleEvent_ValidKapsoSignature(t *testing.T) {
 	}
 }
 
+func TestHandleHealth(t *testing.T) {
+	t.Run("plain ok without hook", func(t *testing.T) {
+		srv := newTestServer()
+		w := httptest.NewRecorder()
+		srv.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("got %d %q, want 200 \"ok\"", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("JSON with hook", func(t *testing.T) {
+		srv := newTestServer()
+		srv.Health = func() map[string]interface{} {
+			return map[string]interface{}{"outbound_queue_depth": 3}
+		}
+		w := httptest.NewRecorder()
+		srv.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+		}
+		if got["status"] != "ok" || got["outbound_queue_depth"] != float64(3) {
+			t.Errorf("health = %v", got)
+		}
+	})
+}
+
 func TestFormatName(t *testing.T) {
 	tests := []struct {
 		f    webhookFormat
@@ -3,8 +3,10 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
 )
 
 // Gateway is the abstraction for AI agent backends (OpenClaw, ZeroClaw, etc.).
@@ -20,6 +22,21 @@ type Gateway interface {
 	Close() error
 }
 
+// ConnState is the state of a gateway's connection.
+type ConnState string
+
+const (
+	StateConnected    ConnState = "connected"
+	StateReconnecting ConnState = "reconnecting"
+	StateDisconnected ConnState = "disconnected" // not connected yet, or closed
+)
+
+// StateReporter is implemented by gateways that keep a connection open and
+// re-establish it when it drops.
+type StateReporter interface {
+	State() ConnState
+}
+
 // Request carries all fields a gateway implementation might need to format and
 // route a message. Each implementation picks the fields it cares about.
 type Request struct {
@@ -28,7 +45,35 @@ type Request struct {
 	From           string // sender phone number (E.164)
 	FromName       string // sender display name
 	Role           string // sender role (admin, member, etc.)
-	Text           string // raw message text
+	Text           string // message rendered as text; the fallback every gateway can send
+
+	// Typed message fields, for gateways that can render them natively.
+	// Text already carries the same information.
+	Type          string                // WhatsApp message type ("text", "image", ...)
+	Timestamp     time.Time             // when the user sent the message; zero if unknown
+	Attachments   []delivery.Attachment // media sent with the message
+	Location      *delivery.Location    // shared location pin
+	ReplyTo       string                // ID of the message the user quoted
+	PhoneNumberID string                // business number that received the message
+}
+
+// NewRequest builds a request for evt with all typed fields set. text is the
+// rendering sent to gateways that only take text.
+func NewRequest(evt delivery.Event, sessionKey, role, text string) *Request {
+	return &Request{
+		SessionKey:     sessionKey,
+		IdempotencyKey: evt.ID,
+		From:           evt.From,
+		FromName:       evt.Name,
+		Role:           role,
+		Text:           text,
+		Type:           evt.Type,
+		Timestamp:      evt.Timestamp,
+		Attachments:    evt.Attachments,
+		Location:       evt.Location,
+		ReplyTo:        evt.ReplyTo,
+		PhoneNumberID:  evt.PhoneNumberID,
+	}
 }
 
 // New creates the appropriate Gateway for the configured type.
@@ -45,13 +90,18 @@ func New(cfg config.GatewayConfig, opts ...Option) (Gateway, error) {
 		return NewOpenClaw(cfg), nil
 	case "zeroclaw":
 		return NewZeroClaw(cfg), nil
+	case "openai":
+		return NewOpenAI(cfg, o.stateDir), nil
+	case "http":
+		return NewHTTP(cfg), nil
 	default:
 		return nil, fmt.Errorf("unknown gateway type: %q", cfg.Type)
 	}
 }
 
 type options struct {
-	signer Signer
+	signer   Signer
+	stateDir string
 }
 
 // Option configures gateway construction.
@@ -61,3 +111,9 @@ type Option func(*options)
 func WithSigner(s Signer) Option {
 	return func(o *options) { o.signer = s }
 }
+
+// WithStateDir sets the directory for gateway state, such as the session
+// histories of the openai gateway.
+func WithStateDir(dir string) Option {
+	return func(o *options) { o.stateDir = dir }
+}
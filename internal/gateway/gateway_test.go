@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+func TestNewRequest(t *testing.T) {
+	evt := delivery.Event{
+		ID:            "wamid.1",
+		From:          "+1555",
+		Name:          "Ana",
+		Text:          "[image] receipt (image/jpeg)",
+		ReplyTo:       "wamid.0",
+		Type:          "image",
+		Timestamp:     time.Unix(1700000000, 0).UTC(),
+		Attachments:   []delivery.Attachment{{Kind: "image", MimeType: "image/jpeg", Caption: "receipt"}},
+		PhoneNumberID: "pn1",
+	}
+
+	req := NewRequest(evt, "main:+1555", "admin", "quoted\n"+evt.Text)
+	if req.SessionKey != "main:+1555" || req.IdempotencyKey != "wamid.1" || req.Role != "admin" {
+		t.Errorf("routing fields: %+v", req)
+	}
+	if req.From != "+1555" || req.FromName != "Ana" || req.Text != "quoted\n[image] receipt (image/jpeg)" {
+		t.Errorf("sender/text fields: %+v", req)
+	}
+	if req.Type != "image" || !req.Timestamp.Equal(evt.Timestamp) || req.ReplyTo != "wamid.0" || req.PhoneNumberID != "pn1" {
+		t.Errorf("typed fields: %+v", req)
+	}
+	if len(req.Attachments) != 1 || req.Attachments[0].Caption != "receipt" || req.Location != nil {
+		t.Errorf("attachments/location: %+v", req)
+	}
+}
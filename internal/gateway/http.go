@@ -0,0 +1,439 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+)
+
+// Requests to an http gateway and its callbacks are signed with the shared
+// secret: SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of
+// "<timestamp>.<body>", where the timestamp is the Unix time in seconds from
+// TimestampHeader.
+const (
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Signature-Timestamp"
+)
+
+// maxCallbackSkew is how far a callback's signed timestamp may be from the
+// bridge's clock, which bounds how long a captured callback can be replayed.
+const maxCallbackSkew = 5 * time.Minute
+
+// maxCallbackBody caps the size of a callback request.
+const maxCallbackBody = 4 * 1024 * 1024
+
+// HTTP implements Gateway for agents behind a plain HTTP endpoint. Each
+// message is POSTed as JSON, signed with the shared secret. The endpoint
+// either answers with the reply (200) or accepts the message (202) and
+// later POSTs the reply to the bridge's callback endpoint, matched by the
+// idempotency key.
+type HTTP struct {
+	url          string
+	token        string
+	secret       string
+	callbackAddr string
+	callbackURL  string // as configured; derived from the listener when empty
+	client       *http.Client
+
+	cb *callbackServer // nil without a callback address
+}
+
+// httpGatewayRequest is the JSON body POSTed to the endpoint.
+type httpGatewayRequest struct {
+	IdempotencyKey string               `json:"idempotency_key"`
+	SessionKey     string               `json:"session_key"`
+	From           string               `json:"from"`
+	FromName       string               `json:"from_name,omitempty"`
+	Role           string               `json:"role,omitempty"`
+	Text           string               `json:"text"`
+	Type           string               `json:"type,omitempty"`
+	Timestamp      *time.Time           `json:"timestamp,omitempty"`
+	Attachments    []httpAttachment     `json:"attachments,omitempty"`
+	Location       *httpGatewayLocation `json:"location,omitempty"`
+	ReplyTo        string               `json:"reply_to,omitempty"`
+	PhoneNumberID  string               `json:"phone_number_id,omitempty"`
+	CallbackURL    string               `json:"callback_url,omitempty"`
+}
+
+type httpAttachment struct {
+	Kind     string `json:"kind"`
+	MimeType string `json:"mime_type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+type httpGatewayLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// httpGatewayReply is a synchronous reply body or a callback body.
+type httpGatewayReply struct {
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Reply          string `json:"reply"`
+	Error          string `json:"error,omitempty"`
+}
+
+// NewHTTP creates an HTTP gateway from config.
+func NewHTTP(cfg config.GatewayConfig) *HTTP {
+	return &HTTP{
+		url:          cfg.URL,
+		token:        cfg.Token,
+		secret:       cfg.Secret,
+		callbackAddr: cfg.CallbackAddr,
+		callbackURL:  cfg.CallbackURL,
+		client:       &http.Client{},
+	}
+}
+
+// Connect starts listening for callbacks. Without a secret callbacks could
+// not be authenticated, so only synchronous replies are accepted then. The
+// endpoint itself only takes messages, so it is not probed.
+func (h *HTTP) Connect(ctx context.Context) error {
+	if h.callbackAddr != "" && h.secret == "" {
+		log.Printf("http gateway: gateway.secret is not set, not listening for callbacks on %s", h.callbackAddr)
+	} else if h.callbackAddr != "" && h.cb == nil {
+		cb, err := acquireCallbackServer(h.callbackAddr, h.secret)
+		if err != nil {
+			return err
+		}
+		h.cb = cb
+		if h.callbackURL == "" {
+			h.callbackURL = cb.url()
+		}
+	}
+	if h.cb != nil {
+		log.Printf("http gateway: posting to %s, callbacks at %s", h.url, h.callbackURL)
+	} else {
+		log.Printf("http gateway: posting to %s, synchronous replies only", h.url)
+	}
+	return nil
+}
+
+// SendAndReceive POSTs the message and returns the reply from the response
+// or, after a 202, from the callback.
+func (h *HTTP) SendAndReceive(ctx context.Context, req *Request) (string, error) {
+	body, err := json.Marshal(h.wireRequest(req))
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Registered before sending, so a fast callback is not missed.
+	var wait *callbackWait
+	if h.cb != nil && req.IdempotencyKey != "" {
+		wait = h.cb.expect(req.IdempotencyKey)
+		defer h.cb.forget(req.IdempotencyKey, wait)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
+	if h.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		httpReq.Header.Set(TimestampHeader, ts)
+		httpReq.Header.Set(SignatureHeader, "sha256="+sign(ts, body, h.secret))
+	}
+	if h.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("post to agent: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return readReply(resp)
+	case http.StatusAccepted:
+		if wait == nil {
+			return "", errors.New("agent answered 202 but the bridge is not listening for callbacks (gateway.callback_addr and gateway.secret)")
+		}
+		select {
+		case <-wait.done:
+			return wait.result()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	default:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("agent returned %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+}
+
+// Close stops listening for callbacks.
+func (h *HTTP) Close() error {
+	if h.cb == nil {
+		return nil
+	}
+	cb := h.cb
+	h.cb = nil
+	return releaseCallbackServer(cb)
+}
+
+func (h *HTTP) wireRequest(req *Request) httpGatewayRequest {
+	out := httpGatewayRequest{
+		IdempotencyKey: req.IdempotencyKey,
+		SessionKey:     req.SessionKey,
+		From:           req.From,
+		FromName:       req.FromName,
+		Role:           req.Role,
+		Text:           req.Text,
+		Type:           req.Type,
+		ReplyTo:        req.ReplyTo,
+		PhoneNumberID:  req.PhoneNumberID,
+	}
+	if h.cb != nil {
+		out.CallbackURL = h.callbackURL
+	}
+	if !req.Timestamp.IsZero() {
+		ts := req.Timestamp.UTC()
+		out.Timestamp = &ts
+	}
+	for _, a := range req.Attachments {
+		out.Attachments = append(out.Attachments, httpAttachment(a))
+	}
+	if l := req.Location; l != nil {
+		out.Location = &httpGatewayLocation{Latitude: l.Latitude, Longitude: l.Longitude, Name: l.Name, Address: l.Address}
+	}
+	return out
+}
+
+// readReply takes the reply from a 200 response: {"reply": "..."} for JSON,
+// otherwise the body as text.
+func readReply(resp *http.Response) (string, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCallbackBody))
+	if err != nil {
+		return "", fmt.Errorf("read reply: %w", err)
+	}
+	reply := string(body)
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/json" {
+		var r httpGatewayReply
+		if err := json.Unmarshal(body, &r); err != nil {
+			return "", fmt.Errorf("decode reply: %w", err)
+		}
+		if r.Error != "" {
+			return "", fmt.Errorf("agent error: %s", r.Error)
+		}
+		reply = r.Reply
+	}
+	if strings.TrimSpace(reply) == "" {
+		return "", errors.New("agent returned an empty reply")
+	}
+	return reply, nil
+}
+
+// sign returns the hex HMAC-SHA256 of "<ts>.<body>".
+func sign(ts string, body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a callback's signature and that its timestamp is recent.
+func verify(headers http.Header, body []byte, secret string, now time.Time) bool {
+	ts := headers.Get(TimestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > maxCallbackSkew || d < -maxCallbackSkew {
+		return false
+	}
+	sig := strings.TrimPrefix(headers.Get(SignatureHeader), "sha256=")
+	return hmac.Equal([]byte(sig), []byte(sign(ts, body, secret)))
+}
+
+// callbackServer receives async replies on /callback. Gateways listening on
+// the same address (numbers with different agent URLs) share one server.
+type callbackServer struct {
+	addr   string
+	secret string
+	ln     net.Listener
+	srv    *http.Server
+	refs   int // guarded by callbackServers.mu
+
+	mu    sync.Mutex
+	waits map[string]*callbackWait
+}
+
+// callbackWait is the pending reply for one idempotency key.
+type callbackWait struct {
+	refs  int // guarded by callbackServer.mu
+	done  chan struct{}
+	once  sync.Once
+	reply string
+	err   error
+}
+
+func (w *callbackWait) result() (string, error) {
+	return w.reply, w.err
+}
+
+var callbackServers struct {
+	mu sync.Mutex
+	m  map[string]*callbackServer
+}
+
+func acquireCallbackServer(addr, secret string) (*callbackServer, error) {
+	callbackServers.mu.Lock()
+	defer callbackServers.mu.Unlock()
+	if cb := callbackServers.m[addr]; cb != nil {
+		cb.refs++
+		return cb, nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("callback listen: %w", err)
+	}
+	cb := &callbackServer{
+		addr:   addr,
+		secret: secret,
+		ln:     ln,
+		refs:   1,
+		waits:  make(map[string]*callbackWait),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", cb.handleCallback)
+	cb.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := cb.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("http gateway: callback server: %v", err)
+		}
+	}()
+	log.Printf("http gateway: listening for callbacks on %s", ln.Addr())
+
+	if callbackServers.m == nil {
+		callbackServers.m = make(map[string]*callbackServer)
+	}
+	callbackServers.m[addr] = cb
+	return cb, nil
+}
+
+func releaseCallbackServer(cb *callbackServer) error {
+	callbackServers.mu.Lock()
+	cb.refs--
+	last := cb.refs == 0
+	if last {
+		delete(callbackServers.m, cb.addr)
+	}
+	callbackServers.mu.Unlock()
+	if !last {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return cb.srv.Shutdown(ctx)
+}
+
+// url is the callback URL derived from the listening address, for agents on
+// the same machine.
+func (cb *callbackServer) url() string {
+	host, port, err := net.SplitHostPort(cb.ln.Addr().String())
+	if err != nil {
+		return "http://" + cb.ln.Addr().String() + "/callback"
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/callback"
+}
+
+func (cb *callbackServer) expect(key string) *callbackWait {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	w := cb.waits[key]
+	if w == nil {
+		w = &callbackWait{done: make(chan struct{})}
+		cb.waits[key] = w
+	}
+	w.refs++
+	return w
+}
+
+func (cb *callbackServer) forget(key string, w *callbackWait) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	w.refs--
+	if w.refs == 0 && cb.waits[key] == w {
+		delete(cb.waits, key)
+	}
+}
+
+// handleCallback delivers an async reply to the request waiting on its
+// idempotency key.
+func (cb *callbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackBody))
+	if err != nil {
+		http.Error(w, "read error", http.StatusBadRequest)
+		return
+	}
+	if !verify(r.Header, body, cb.secret, time.Now()) {
+		log.Printf("http gateway: rejected callback with invalid or expired signature")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var reply httpGatewayReply
+	if err := json.Unmarshal(body, &reply); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	key := reply.IdempotencyKey
+	if key == "" {
+		key = r.Header.Get("Idempotency-Key")
+	}
+
+	cb.mu.Lock()
+	wait := cb.waits[key]
+	cb.mu.Unlock()
+	if wait == nil {
+		// Unknown, or the bridge stopped waiting (timeout, restart).
+		log.Printf("http gateway: callback for %q has no waiting request", key)
+		http.Error(w, "no request waiting for this idempotency key", http.StatusNotFound)
+		return
+	}
+
+	wait.once.Do(func() {
+		switch {
+		case reply.Error != "":
+			wait.err = fmt.Errorf("agent error: %s", reply.Error)
+		case strings.TrimSpace(reply.Reply) == "":
+			wait.err = errors.New("agent returned an empty reply")
+		default:
+			wait.reply = reply.Reply
+		}
+		close(wait.done)
+	})
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// signedPost posts body to url with an HMAC signature made with secret at
+// time at.
+func signedPost(t *testing.T, url, secret string, at time.Time, body []byte) int {
+	t.Helper()
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, "sha256="+sign(ts, body, secret))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Errorf("callback: %v", err)
+		return 0
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHTTPGatewaySyncReply(t *testing.T) {
+	var got httpGatewayRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !verify(r.Header, body, "s3cret", time.Now()) {
+			t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get("Idempotency-Key") != "wamid.1" {
+			t.Errorf("Idempotency-Key = %q", r.Header.Get("Idempotency-Key"))
+		}
+		_ = json.Unmarshal(body, &got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"reply":"hello back"}`))
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(config.GatewayConfig{URL: srv.URL, Secret: "s3cret"})
+	if err := h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = h.Close() }()
+
+	reply, err := h.SendAndReceive(context.Background(), &Request{
+		IdempotencyKey: "wamid.1",
+		SessionKey:     "main",
+		From:           "+1555",
+		Text:           "[image] receipt",
+		Type:           "image",
+		Timestamp:      time.Unix(1700000000, 0),
+		Attachments:    []delivery.Attachment{{Kind: "image", MimeType: "image/jpeg", URL: "file:///tmp/a.jpg"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "hello back" {
+		t.Errorf("reply = %q", reply)
+	}
+	if got.IdempotencyKey != "wamid.1" || got.From != "+1555" || got.Type != "image" || got.CallbackURL != "" {
+		t.Errorf("request = %+v", got)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0].MimeType != "image/jpeg" || got.Timestamp == nil {
+		t.Errorf("typed fields = %+v", got)
+	}
+}
+
+func TestHTTPGatewayPlainTextAndErrors(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("plain reply"))
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(config.GatewayConfig{URL: srv.URL})
+	reply, err := h.SendAndReceive(context.Background(), &Request{IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil || reply != "plain reply" {
+		t.Errorf("plain text: %q, %v", reply, err)
+	}
+
+	status = http.StatusBadGateway
+	if _, err := h.SendAndReceive(context.Background(), &Request{IdempotencyKey: "wamid.2", Text: "hi"}); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected a status error, got %v", err)
+	}
+
+	status = http.StatusAccepted
+	if _, err := h.SendAndReceive(context.Background(), &Request{IdempotencyKey: "wamid.3", Text: "hi"}); err == nil || !strings.Contains(err.Error(), "callback") {
+		t.Errorf("202 without a callback endpoint: got %v", err)
+	}
+}
+
+func TestHTTPGatewayAsyncCallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req httpGatewayRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			// A forged callback is refused; the genuine one is delivered.
+			body := []byte(fmt.Sprintf(`{"idempotency_key":%q,"reply":"forged"}`, req.IdempotencyKey))
+			if code := signedPost(t, req.CallbackURL, "wrong", time.Now(), body); code != http.StatusUnauthorized {
+				t.Errorf("forged callback: status %d, want 401", code)
+			}
+			// A captured callback replayed later is refused too.
+			if code := signedPost(t, req.CallbackURL, "s3cret", time.Now().Add(-time.Hour), body); code != http.StatusUnauthorized {
+				t.Errorf("stale callback: status %d, want 401", code)
+			}
+			body = []byte(fmt.Sprintf(`{"idempotency_key":%q,"reply":"done later"}`, req.IdempotencyKey))
+			if code := signedPost(t, req.CallbackURL, "s3cret", time.Now(), body); code != http.StatusOK {
+				t.Errorf("callback: status %d", code)
+			}
+		}()
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(config.GatewayConfig{URL: srv.URL, Secret: "s3cret", CallbackAddr: "127.0.0.1:0"})
+	if err := h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = h.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := h.SendAndReceive(ctx, &Request{IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "done later" {
+		t.Errorf("reply = %q", reply)
+	}
+
+	// Nobody waits for this key any more.
+	body := []byte(`{"idempotency_key":"wamid.1","reply":"again"}`)
+	if code := signedPost(t, h.callbackURL, "s3cret", time.Now(), body); code != http.StatusNotFound {
+		t.Errorf("late callback: status %d, want 404", code)
+	}
+}
+
+func TestHTTPGatewayNoCallbacksWithoutSecret(t *testing.T) {
+	h := NewHTTP(config.GatewayConfig{URL: "http://127.0.0.1:1/", CallbackAddr: "127.0.0.1:0"})
+	if err := h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = h.Close() }()
+	if h.cb != nil {
+		t.Error("callback server started without a secret")
+	}
+}
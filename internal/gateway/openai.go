@@ -0,0 +1,468 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+)
+
+// maxInlineImage caps the size of a local image sent inline as a data URL.
+const maxInlineImage = 5 * 1024 * 1024
+
+// sessionIdle is how long an unused session stays in memory. Its history
+// is reloaded from disk when the sender writes again.
+const sessionIdle = time.Hour
+
+// OpenAI implements Gateway for OpenAI-compatible /v1/chat/completions
+// endpoints (OpenAI, llama.cpp server, vLLM, Ollama, ...). The endpoint is
+// stateless, so the conversation history of each session is kept by the
+// bridge, in <state.dir>/openai-sessions when a state dir is set.
+type OpenAI struct {
+	baseURL    string // up to and including /v1
+	token      string
+	model      string
+	prompt     string
+	turns      int
+	tokens     int
+	stream     bool
+	sessionKey string
+	dir        string // history directory; empty keeps history in memory
+	client     *http.Client
+
+	mu       sync.Mutex // guards sessions and the users and used fields of each
+	sessions map[string]*openAISession
+}
+
+// openAISession is the history of one session. Its mutex serialises turns,
+// so each request sees the previous reply.
+type openAISession struct {
+	mu       sync.Mutex
+	loaded   bool
+	messages []historyMessage
+
+	users int       // requests holding the session
+	used  time.Time // when the last request released it
+}
+
+// historyMessage is one stored message. History is kept as text only;
+// images are sent inline with the turn they arrive in.
+type historyMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // a string, or content parts
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type chatCompletionRequest struct {
+	Model    string                  `json:"model,omitempty"`
+	Messages []chatCompletionMessage `json:"messages"`
+	Stream   bool                    `json:"stream,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAI creates an OpenAI-compatible gateway from config. stateDir holds
+// the session histories; empty keeps them in memory.
+func NewOpenAI(cfg config.GatewayConfig, stateDir string) *OpenAI {
+	base := strings.TrimRight(cfg.URL, "/")
+	base = strings.TrimSuffix(base, "/chat/completions")
+
+	o := &OpenAI{
+		baseURL:    base,
+		token:      cfg.Token,
+		model:      cfg.Model,
+		prompt:     cfg.SystemPrompt,
+		turns:      cfg.HistoryTurns,
+		tokens:     cfg.HistoryTokens,
+		stream:     cfg.Stream,
+		sessionKey: cfg.SessionKey,
+		client:     &http.Client{},
+		sessions:   make(map[string]*openAISession),
+	}
+	if stateDir != "" {
+		o.dir = filepath.Join(stateDir, "openai-sessions")
+	}
+	return o
+}
+
+// Connect checks that the endpoint is reachable by listing its models, and
+// picks the first one when no model is configured. Servers without a
+// /models endpoint are accepted as long as a model is set.
+func (o *OpenAI) Connect(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	o.authorize(req)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", o.baseURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if resp.StatusCode == http.StatusOK {
+		_ = json.NewDecoder(resp.Body).Decode(&models)
+	} else if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("connect to %s: status %d (check gateway.token)", o.baseURL, resp.StatusCode)
+	}
+
+	if o.model == "" {
+		if len(models.Data) == 0 {
+			return fmt.Errorf("no gateway.model set and %s/models lists none", o.baseURL)
+		}
+		o.model = models.Data[0].ID
+	}
+
+	log.Printf("openai: using model %s at %s (history: %d turns, stream: %v)", o.model, o.baseURL, o.turns, o.stream)
+	return nil
+}
+
+// SendAndReceive sends the message with the session's history and returns
+// the completion. Turns of one session are serialised.
+func (o *OpenAI) SendAndReceive(ctx context.Context, req *Request) (string, error) {
+	key := req.SessionKey
+	if key == "" {
+		key = o.sessionKey
+	}
+	s := o.session(key)
+	defer o.release(s)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		msgs, err := o.loadHistory(key)
+		if err != nil {
+			log.Printf("openai: starting session %s without history: %v", key, err)
+		}
+		s.messages, s.loaded = msgs, true
+	}
+
+	system := o.systemPrompt(req)
+	history := trimHistory(s.messages, o.turns, o.tokens, estimateTokens(system)+estimateTokens(req.Text))
+	msgs := make([]chatCompletionMessage, 0, len(history)+2)
+	msgs = append(msgs, chatCompletionMessage{Role: "system", Content: system})
+	for _, m := range history {
+		msgs = append(msgs, chatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+	msgs = append(msgs, chatCompletionMessage{Role: "user", Content: userContent(req)})
+
+	reply, err := o.complete(ctx, msgs)
+	if err != nil {
+		return "", err
+	}
+
+	s.messages = trimHistory(append(history,
+		historyMessage{Role: "user", Content: req.Text},
+		historyMessage{Role: "assistant", Content: reply},
+	), o.turns, o.tokens, 0)
+	if err := o.saveHistory(key, s.messages); err != nil {
+		log.Printf("openai: %v", err)
+	}
+	return reply, nil
+}
+
+// Close is a no-op; requests do not hold connections open.
+func (o *OpenAI) Close() error {
+	return nil
+}
+
+// session returns the session for key, which the caller must release. Other
+// sessions left unused for sessionIdle are dropped; a dropped session's
+// history is reloaded from disk, or starts empty without a state dir.
+func (o *OpenAI) session(key string) *openAISession {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for k, s := range o.sessions {
+		if s.users == 0 && now.Sub(s.used) >= sessionIdle {
+			delete(o.sessions, k)
+		}
+	}
+	s := o.sessions[key]
+	if s == nil {
+		s = &openAISession{}
+		o.sessions[key] = s
+	}
+	s.users++
+	return s
+}
+
+func (o *OpenAI) release(s *openAISession) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s.users--
+	s.used = time.Now()
+}
+
+func (o *OpenAI) authorize(req *http.Request) {
+	if o.token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+}
+
+// systemPrompt fills the sender's name, number and role into the prompt.
+func (o *OpenAI) systemPrompt(req *Request) string {
+	name := req.FromName
+	if name == "" {
+		name = req.From
+	}
+	return strings.NewReplacer(
+		"{name}", name,
+		"{from}", req.From,
+		"{role}", req.Role,
+	).Replace(o.prompt)
+}
+
+// userContent renders the message. Images are attached as image parts for
+// vision models; everything else is already in the text.
+func userContent(req *Request) interface{} {
+	var images []contentPart
+	for _, a := range req.Attachments {
+		if a.Kind != "image" {
+			continue
+		}
+		if u := imageDataURL(a.URL, a.MimeType); u != "" {
+			images = append(images, contentPart{Type: "image_url", ImageURL: &imageURL{URL: u}})
+		}
+	}
+	if len(images) == 0 {
+		return req.Text
+	}
+	return append([]contentPart{{Type: "text", Text: req.Text}}, images...)
+}
+
+// imageDataURL inlines a local file from the media store as a data URL.
+// Remote URLs are skipped: Kapso media links need the API key and expire, so
+// the endpoint could not fetch them.
+func imageDataURL(raw, mimeType string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	info, err := os.Stat(u.Path)
+	if err != nil || info.Size() > maxInlineImage {
+		return ""
+	}
+	data, err := os.ReadFile(u.Path)
+	if err != nil {
+		return ""
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// complete posts one chat completion request and returns the reply text.
+func (o *OpenAI) complete(ctx context.Context, msgs []chatCompletionMessage) (string, error) {
+	body, err := json.Marshal(chatCompletionRequest{Model: o.model, Messages: msgs, Stream: o.stream})
+	if err != nil {
+		return "", fmt.Errorf("marshal chat completion: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	o.authorize(req)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("chat completion: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("chat completion: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var reply string
+	if o.stream {
+		reply, err = readStream(resp.Body)
+	} else {
+		var out chatCompletionResponse
+		if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			err = fmt.Errorf("decode chat completion: %w", err)
+		} else if out.Error != nil {
+			err = fmt.Errorf("chat completion: %s", out.Error.Message)
+		} else if len(out.Choices) > 0 {
+			reply = out.Choices[0].Message.Content
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(reply) == "" {
+		return "", errors.New("chat completion returned no content")
+	}
+	return reply, nil
+}
+
+// readStream assembles a streamed (server-sent events) completion.
+func readStream(r io.Reader) (string, error) {
+	var b strings.Builder
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return b.String(), nil
+		}
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("decode chat completion chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", fmt.Errorf("chat completion: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) > 0 {
+			b.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", fmt.Errorf("read chat completion stream: %w", err)
+	}
+	// Some servers end the stream without [DONE].
+	return b.String(), nil
+}
+
+// trimHistory keeps the most recent exchanges within the turn budget and the
+// token budget (estimated at four characters per token; 0 = unlimited).
+// reserved tokens of the budget are taken by the rest of the request, the
+// system prompt and the new message; images are not counted. History is
+// stored in user/assistant pairs and trimmed a pair at a time.
+func trimHistory(msgs []historyMessage, turns, tokens, reserved int) []historyMessage {
+	if turns <= 0 {
+		return nil
+	}
+	if len(msgs) > 2*turns {
+		msgs = msgs[len(msgs)-2*turns:]
+	}
+	if tokens > 0 {
+		total := reserved
+		for _, m := range msgs {
+			total += estimateTokens(m.Content)
+		}
+		for len(msgs) >= 2 && total > tokens {
+			total -= estimateTokens(msgs[0].Content) + estimateTokens(msgs[1].Content)
+			msgs = msgs[2:]
+		}
+	}
+	return msgs
+}
+
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// historyPath names history files by a hash of the session key, which may
+// contain characters that are not safe in file names.
+func (o *OpenAI) historyPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(o.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func (o *OpenAI) loadHistory(key string) ([]historyMessage, error) {
+	if o.dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(o.historyPath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	var h struct {
+		Messages []historyMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("parse history: %w", err)
+	}
+	return h.Messages, nil
+}
+
+func (o *OpenAI) saveHistory(key string, msgs []historyMessage) error {
+	if o.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(o.dir, 0o700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	data, err := json.Marshal(struct {
+		SessionKey string           `json:"session_key"`
+		Messages   []historyMessage `json:"messages"`
+	}{key, msgs})
+	if err != nil {
+		return fmt.Errorf("encode history: %w", err)
+	}
+	tmp, err := os.CreateTemp(o.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), o.historyPath(key)); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
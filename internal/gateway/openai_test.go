@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// openAITestServer serves /v1/models and answers /v1/chat/completions with
+// reply, recording each completion request.
+func openAITestServer(t *testing.T, reply func(req chatCompletionRequest, w http.ResponseWriter)) (string, func() []json.RawMessage) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"data":[{"id":"llama-3"},{"id":"other"}]}`))
+		case "/v1/chat/completions":
+			var raw json.RawMessage
+			_ = json.NewDecoder(r.Body).Decode(&raw)
+			mu.Lock()
+			bodies = append(bodies, raw)
+			mu.Unlock()
+			var req chatCompletionRequest
+			_ = json.Unmarshal(raw, &req)
+			reply(req, w)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/v1", func() []json.RawMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]json.RawMessage(nil), bodies...)
+	}
+}
+
+func TestOpenAIKeepsSessionHistory(t *testing.T) {
+	n := 0
+	url, requests := openAITestServer(t, func(req chatCompletionRequest, w http.ResponseWriter) {
+		n++
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"answer %d"}}]}`, n)
+	})
+
+	cfg := config.GatewayConfig{
+		Type:         "openai",
+		URL:          url + "/chat/completions",
+		Token:        "sk-test",
+		SessionKey:   "main",
+		SystemPrompt: "Talking to {name} ({from}) as {role}.",
+		HistoryTurns: 1,
+	}
+	dir := t.TempDir()
+	o := NewOpenAI(cfg, dir)
+	if err := o.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if o.model != "llama-3" {
+		t.Errorf("model = %q, want the first listed", o.model)
+	}
+
+	for i, text := range []string{"one", "two", "three"} {
+		reply, err := o.SendAndReceive(context.Background(), &Request{SessionKey: "main:+1555", From: "+1555", FromName: "Ana", Role: "admin", Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("answer %d", i+1); reply != want {
+			t.Errorf("reply = %q, want %q", reply, want)
+		}
+	}
+
+	var last chatCompletionRequest
+	bodies := requests()
+	if err := json.Unmarshal(bodies[2], &last); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range last.Messages {
+		got = append(got, fmt.Sprintf("%s:%v", m.Role, m.Content))
+	}
+	want := []string{"system:Talking to Ana (+1555) as admin.", "user:two", "assistant:answer 2", "user:three"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q (one turn of history)", got, want)
+	}
+
+	// A new gateway on the same state dir picks the history up.
+	o2 := NewOpenAI(cfg, dir)
+	o2.model = "llama-3"
+	if _, err := o2.SendAndReceive(context.Background(), &Request{SessionKey: "main:+1555", Text: "four"}); err != nil {
+		t.Fatal(err)
+	}
+	bodies = requests()
+	if !strings.Contains(string(bodies[3]), "answer 3") {
+		t.Errorf("history not restored from %s: %s", dir, bodies[3])
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	url, _ := openAITestServer(t, func(req chatCompletionRequest, w http.ResponseWriter) {
+		if !req.Stream {
+			t.Error("stream not requested")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"Hel", "lo", " there"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	o := NewOpenAI(config.GatewayConfig{URL: url, Token: "sk-test", Model: "m", Stream: true, HistoryTurns: 5}, "")
+	reply, err := o.SendAndReceive(context.Background(), &Request{SessionKey: "main", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Hello there" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+func TestOpenAIErrorStatus(t *testing.T) {
+	url, _ := openAITestServer(t, func(req chatCompletionRequest, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"context length exceeded"}}`))
+	})
+
+	o := NewOpenAI(config.GatewayConfig{URL: url, Token: "sk-test", Model: "m", HistoryTurns: 5}, "")
+	_, err := o.SendAndReceive(context.Background(), &Request{SessionKey: "main", Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "context length exceeded") {
+		t.Fatalf("expected the endpoint's error, got %v", err)
+	}
+	if h := o.session("main").messages; len(h) != 0 {
+		t.Errorf("failed turn was added to history: %+v", h)
+	}
+}
+
+func TestOpenAIInlinesLocalImages(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(img, []byte("\x89PNG\r\n\x1a\nfake"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	req := &Request{
+		Text: "[image] file://" + img,
+		Attachments: []delivery.Attachment{
+			{Kind: "image", MimeType: "image/png", URL: "file://" + img},
+			{Kind: "document", URL: "file:///tmp/doc.pdf"},
+			{Kind: "image", URL: "https://api.kapso.ai/media/123"}, // not fetchable by the endpoint
+		},
+	}
+
+	parts, ok := userContent(req).([]contentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("content = %#v, want text and one image part", userContent(req))
+	}
+	if parts[0].Text != req.Text || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("parts = %+v", parts)
+	}
+
+	if c, ok := userContent(&Request{Text: "plain"}).(string); !ok || c != "plain" {
+		t.Errorf("text-only message should stay a string, got %#v", c)
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	var msgs []historyMessage
+	for i := 0; i < 4; i++ {
+		msgs = append(msgs,
+			historyMessage{Role: "user", Content: strings.Repeat("u", 40)},
+			historyMessage{Role: "assistant", Content: strings.Repeat("a", 40)},
+		)
+	}
+
+	if got := trimHistory(msgs, 0, 0, 0); len(got) != 0 {
+		t.Errorf("0 turns: kept %d messages", len(got))
+	}
+	if got := trimHistory(msgs, 3, 0, 0); len(got) != 6 {
+		t.Errorf("3 turns: kept %d messages, want 6", len(got))
+	}
+	// Each exchange is ~20 tokens.
+	if got := trimHistory(msgs, 10, 45, 0); len(got) != 4 || got[0].Role != "user" {
+		t.Errorf("45 tokens: kept %+v, want the last two exchanges", got)
+	}
+	// The prompt and new message take their share of the budget.
+	if got := trimHistory(msgs, 10, 45, 10); len(got) != 2 {
+		t.Errorf("45 tokens with 10 reserved: kept %d messages, want 2", len(got))
+	}
+	if got := trimHistory(msgs, 10, 45, 50); len(got) != 0 {
+		t.Errorf("45 tokens with 50 reserved: kept %d messages, want 0", len(got))
+	}
+}
+
+func TestOpenAIEvictsIdleSessions(t *testing.T) {
+	o := NewOpenAI(config.GatewayConfig{}, "")
+
+	idle := o.session("idle")
+	o.release(idle)
+	idle.used = time.Now().Add(-2 * sessionIdle)
+	busy := o.session("busy")
+	busy.used = idle.used // still held by a request
+
+	o.release(o.session("new"))
+	if _, ok := o.sessions["idle"]; ok {
+		t.Error("idle session kept")
+	}
+	if _, ok := o.sessions["busy"]; !ok {
+		t.Error("session in use evicted")
+	}
+	o.release(busy)
+}
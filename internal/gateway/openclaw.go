@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/config"
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
 	"github.com/gorilla/websocket"
 )
 
@@ -26,12 +28,33 @@ type requestFrame struct {
 }
 
 type responseFrame struct {
-	Type   string          `json:"type"`
-	ID     string          `json:"id,omitempty"`
-	Method string          `json:"method,omitempty"`
-	Params json.RawMessage `json:"params,omitempty"`
-	Result json.RawMessage `json:"result,omitempty"`
-	Error  json.RawMessage `json:"error,omitempty"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// eventName returns the name of an event frame. Gateways name it in "event"
+// or, in older versions, in "method".
+func (f responseFrame) eventName() string {
+	if f.Event != "" {
+		return f.Event
+	}
+	return f.Method
+}
+
+// body returns the frame's data, wherever this gateway version puts it.
+func (f responseFrame) body() json.RawMessage {
+	for _, b := range []json.RawMessage{f.Payload, f.Result, f.Params} {
+		if len(b) > 0 {
+			return b
+		}
+	}
+	return nil
 }
 
 type connectParams struct {
@@ -78,6 +101,106 @@ type chatSendParams struct {
 	IdempotencyKey string `json:"idempotencyKey"`
 }
 
+type chatSubscribeParams struct {
+	SessionKey string `json:"sessionKey"`
+}
+
+type chatHistoryParams struct {
+	SessionKey string `json:"sessionKey"`
+	Limit      int    `json:"limit"`
+}
+
+// historyLimit is how many of the latest session messages chat.history is
+// asked for when looking up a reply.
+const historyLimit = 20
+
+// chatEvent is the payload of a "chat" event frame: the progress of one
+// agent run. Deltas carry the assistant text so far; the run ends with a
+// "final", "aborted" or "error" state.
+type chatEvent struct {
+	RunID        string       `json:"runId"`
+	SessionKey   string       `json:"sessionKey"`
+	State        string       `json:"state"`
+	Message      *chatMessage `json:"message,omitempty"`
+	ErrorMessage string       `json:"errorMessage,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // a string or a list of content blocks
+}
+
+// text returns the message's text content.
+func (m *chatMessage) text() string {
+	if m == nil || len(m.Content) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// chatRun collects the chat events of one run for the SendAndReceive call
+// waiting on it.
+type chatRun struct {
+	mu      sync.Mutex
+	text    string // latest assistant text
+	err     error
+	seen    chan struct{} // closed at the first event
+	done    chan struct{} // closed when the run ends
+	seenOne sync.Once
+	once    sync.Once
+}
+
+func newChatRun() *chatRun {
+	return &chatRun{seen: make(chan struct{}), done: make(chan struct{})}
+}
+
+// update applies one event to the run.
+func (r *chatRun) update(ev chatEvent) {
+	r.seenOne.Do(func() { close(r.seen) })
+	r.mu.Lock()
+	if t := ev.Message.text(); t != "" {
+		r.text = t
+	}
+	switch ev.State {
+	case "final":
+		if r.text == "" {
+			r.err = fmt.Errorf("agent run %s finished without a reply", ev.RunID)
+		}
+	case "aborted":
+		r.err = fmt.Errorf("agent run %s aborted", ev.RunID)
+	case "error":
+		r.err = fmt.Errorf("agent run %s failed: %s", ev.RunID, ev.ErrorMessage)
+	default:
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	r.once.Do(func() { close(r.done) })
+}
+
+func (r *chatRun) result() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.text, r.err
+}
+
 // Version is the bridge version sent in the connect handshake.
 // Overridden at build time via -ldflags.
 var Version = "dev"
@@ -134,6 +257,25 @@ type OpenClaw struct {
 	pending map[string]chan responseFrame
 	pendMu  sync.Mutex    // guards pending map (separate from mu)
 	done    chan struct{} // closed when readLoop exits
+
+	// Reply routing: readLoop hands "chat" events to the run they belong to.
+	runs       map[string]*chatRun  // by run ID
+	early      map[string]*earlyRun // events of runs not tracked yet, see trackRun
+	subscribed map[string]bool      // session keys subscribed to chat events
+	runsMu     sync.Mutex
+	quiet      time.Duration // wait for a run's first event before polling; 0 = 1m
+
+	pollInterval time.Duration // session JSONL fallback; 0 = 3s
+	tails        sessionTails
+
+	// Connection supervision, see reconnect. Guarded by mu.
+	state      ConnState
+	up         chan struct{} // closed while connected, replaced when the connection drops
+	closing    chan struct{} // closed by Close to stop reconnecting
+	supervisor sync.WaitGroup
+	backoff    time.Duration // first reconnect delay; 0 = 1s
+	maxBackoff time.Duration // 0 = 1m
+	grace      time.Duration // how long SendAndReceive waits for a reconnect; 0 = 30s
 }
 
 // NewOpenClaw creates an OpenClaw gateway from config.
@@ -162,28 +304,43 @@ func (oc *OpenClaw) nextID() string {
 }
 
 // Connect establishes the WebSocket connection and completes the
-// challenge-response auth handshake with the OpenClaw gateway.
+// challenge-response auth handshake with the OpenClaw gateway. When the
+// connection drops later, it is re-established in the background until
+// Close is called.
 func (oc *OpenClaw) Connect(ctx context.Context) error {
+	conn, err := oc.handshake(ctx)
+	if err != nil {
+		return err
+	}
+
 	oc.mu.Lock()
 	defer oc.mu.Unlock()
+	oc.closing = make(chan struct{})
+	oc.up = make(chan struct{})
+	oc.attach(conn)
 
+	log.Printf("authenticated with gateway at %s", oc.url)
+	return nil
+}
+
+// handshake dials the gateway and completes the challenge-response auth
+// handshake, returning a connection ready for readLoop.
+func (oc *OpenClaw) handshake(ctx context.Context) (*websocket.Conn, error) {
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
 	}
 
 	conn, _, err := dialer.DialContext(ctx, oc.url, nil)
 	if err != nil {
-		return fmt.Errorf("connect to gateway: %w", err)
+		return nil, fmt.Errorf("connect to gateway: %w", err)
 	}
-	oc.conn = conn
 
 	// Read the challenge from the gateway.
 	_ = conn.SetReadDeadline(time.Now().Add(15 * time.Second))
 	_, msg, err := conn.ReadMessage()
 	if err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("read challenge: %w", err)
+		return nil, fmt.Errorf("read challenge: %w", err)
 	}
 
 	log.Printf("received challenge from gateway (%d bytes)", len(msg))
@@ -196,8 +353,7 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 	}
 	if err := json.Unmarshal(msg, &challenge); err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("parse challenge frame: %w", err)
+		return nil, fmt.Errorf("parse challenge frame: %w", err)
 	}
 
 	clientID := "gateway-client"
@@ -214,8 +370,7 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 		nonce := challenge.Payload.Nonce
 		if nonce == "" {
 			_ = conn.Close()
-			oc.conn = nil
-			return fmt.Errorf("gateway challenge missing nonce")
+			return nil, fmt.Errorf("gateway challenge missing nonce")
 		}
 		signedAt := time.Now().UnixMilli()
 		payload := buildDeviceAuthPayloadV3(oc.signer.DeviceID(), clientID, clientMode, role, oc.token, scopes, signedAt, nonce, platform, "")
@@ -230,9 +385,12 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 	}
 
 	// Send connect request.
+	oc.mu.Lock()
+	id := oc.nextID()
+	oc.mu.Unlock()
 	connectReq := requestFrame{
 		Type:   "req",
-		ID:     oc.nextID(),
+		ID:     id,
 		Method: "connect",
 		Params: connectParams{
 			MinProtocol: 3,
@@ -256,16 +414,14 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 	data, err := json.Marshal(connectReq)
 	if err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("marshal connect request: %w", err)
+		return nil, fmt.Errorf("marshal connect request: %w", err)
 	}
 
 	log.Printf("sending connect request")
 
 	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("send connect: %w", err)
+		return nil, fmt.Errorf("send connect: %w", err)
 	}
 
 	// Wait for response.
@@ -273,8 +429,7 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 	_, msg, err = conn.ReadMessage()
 	if err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("read connect response: %w", err)
+		return nil, fmt.Errorf("read connect response: %w", err)
 	}
 
 	log.Printf("received connect response (%d bytes)", len(msg))
@@ -282,33 +437,53 @@ func (oc *OpenClaw) Connect(ctx context.Context) error {
 	var resp responseFrame
 	if err := json.Unmarshal(msg, &resp); err != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("parse connect response: %w", err)
+		return nil, fmt.Errorf("parse connect response: %w", err)
 	}
 
 	if resp.Error != nil {
 		_ = conn.Close()
-		oc.conn = nil
-		return fmt.Errorf("connect rejected: %s", string(resp.Error))
+		return nil, fmt.Errorf("connect rejected: %s", string(resp.Error))
 	}
 
 	// Clear deadline for normal operation.
 	_ = conn.SetReadDeadline(time.Time{})
+	return conn, nil
+}
 
-	// Initialize response routing for the new connection.
-	oc.pending = make(map[string]chan responseFrame)
+// attach makes conn the active connection and starts reading from it.
+// Caller must hold mu.
+func (oc *OpenClaw) attach(conn *websocket.Conn) {
+	oc.conn = conn
+	// pending outlives connections: sendRequest touches it under pendMu
+	// only, and readLoop clears a dropped connection's entries.
+	oc.pendMu.Lock()
+	if oc.pending == nil {
+		oc.pending = make(map[string]chan responseFrame)
+	}
+	oc.pendMu.Unlock()
 	oc.done = make(chan struct{})
-	go oc.readLoop()
-
-	log.Printf("authenticated with gateway at %s", oc.url)
-	return nil
+	go oc.readLoop(conn, oc.done)
+	close(oc.up)
+	oc.setState(StateConnected)
 }
 
 // readLoop reads incoming frames and routes "res" frames to pending callers.
 // All other frames (events) are logged for observability. This is the sole
-// goroutine that reads from the WebSocket connection.
-func (oc *OpenClaw) readLoop() {
+// goroutine that reads from the WebSocket connection. done is closed when it
+// exits; if the connection dropped without Close, a reconnect is started.
+func (oc *OpenClaw) readLoop(conn *websocket.Conn, done chan struct{}) {
 	defer func() {
+		oc.mu.Lock()
+		lost := oc.conn == conn
+		if lost {
+			oc.conn = nil
+			oc.up = make(chan struct{})
+			oc.setState(StateReconnecting)
+			oc.supervisor.Add(1)
+		}
+		closing := oc.closing
+		oc.mu.Unlock()
+
 		// Signal all pending sendRequest callers that the connection is gone.
 		oc.pendMu.Lock()
 		for id, ch := range oc.pending {
@@ -316,17 +491,14 @@ func (oc *OpenClaw) readLoop() {
 			delete(oc.pending, id)
 		}
 		oc.pendMu.Unlock()
-		close(oc.done)
-	}()
+		close(done)
 
-	for {
-		oc.mu.Lock()
-		conn := oc.conn
-		oc.mu.Unlock()
-		if conn == nil {
-			return
+		if lost {
+			go oc.reconnect(closing)
 		}
+	}()
 
+	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
 			return
@@ -349,7 +521,185 @@ func (oc *OpenClaw) readLoop() {
 			continue
 		}
 
-		log.Printf("gateway event: type=%s method=%s (%d bytes)", frame.Type, frame.Method, len(msg))
+		if frame.Type == "event" && frame.eventName() == "chat" {
+			var ev chatEvent
+			if err := json.Unmarshal(frame.body(), &ev); err != nil {
+				log.Printf("openclaw: ignoring malformed chat event: %v", err)
+				continue
+			}
+			oc.routeChatEvent(ev)
+			continue
+		}
+
+		log.Printf("gateway event: type=%s event=%s (%d bytes)", frame.Type, frame.eventName(), len(msg))
+	}
+}
+
+// subscribe asks the gateway for the chat events of sessionKey, once per
+// session. Gateways that broadcast chat events to every operator, or that do
+// not know the method, answer with an error, which is harmless.
+func (oc *OpenClaw) subscribe(ctx context.Context, sessionKey string) {
+	oc.runsMu.Lock()
+	if oc.subscribed == nil {
+		oc.subscribed = make(map[string]bool)
+	}
+	done := oc.subscribed[sessionKey]
+	oc.subscribed[sessionKey] = true
+	oc.runsMu.Unlock()
+	if done {
+		return
+	}
+
+	resp, err := oc.sendRequest(ctx, "chat.subscribe", chatSubscribeParams{SessionKey: sessionKey})
+	if err == nil && resp.Error != nil {
+		err = fmt.Errorf("%s", string(resp.Error))
+	}
+	if isDisconnect(err) {
+		// Try again on the next connection.
+		oc.runsMu.Lock()
+		delete(oc.subscribed, sessionKey)
+		oc.runsMu.Unlock()
+	}
+	if err != nil {
+		log.Printf("openclaw: chat.subscribe %s: %v", sessionKey, err)
+	}
+}
+
+// maxEarlyRuns caps how many untracked runs have their events kept, and
+// earlyRetention how long they are kept.
+const (
+	maxEarlyRuns   = 100
+	earlyRetention = time.Minute
+)
+
+// earlyRun holds the events of a run that is not tracked yet.
+type earlyRun struct {
+	events []chatEvent
+	first  time.Time // arrival of the first event
+}
+
+// routeChatEvent hands ev to its run. Events of an unknown run are kept for
+// a while: when the gateway picks its own run ID, its first events can
+// arrive before SendAndReceive has read the ID from the chat.send response.
+func (oc *OpenClaw) routeChatEvent(ev chatEvent) {
+	oc.runsMu.Lock()
+	run := oc.runs[ev.RunID]
+	if run == nil {
+		oc.keepEarly(ev, time.Now())
+	}
+	oc.runsMu.Unlock()
+	if run != nil {
+		run.update(ev)
+	}
+}
+
+// keepEarly stores ev for a run that is not tracked yet. Runs whose events
+// are older than earlyRetention are dropped, and the oldest one when
+// maxEarlyRuns are kept. Caller must hold runsMu.
+func (oc *OpenClaw) keepEarly(ev chatEvent, now time.Time) {
+	if oc.early == nil {
+		oc.early = make(map[string]*earlyRun)
+	}
+	e := oc.early[ev.RunID]
+	if e == nil {
+		var oldest string
+		for id, r := range oc.early {
+			if now.Sub(r.first) >= earlyRetention {
+				delete(oc.early, id)
+			} else if oldest == "" || r.first.Before(oc.early[oldest].first) {
+				oldest = id
+			}
+		}
+		if len(oc.early) >= maxEarlyRuns {
+			delete(oc.early, oldest)
+		}
+		e = &earlyRun{first: now}
+		oc.early[ev.RunID] = e
+	}
+	e.events = append(e.events, ev)
+}
+
+// trackRun registers run under id so readLoop can route its events, and
+// applies the events that arrived before.
+func (oc *OpenClaw) trackRun(id string, run *chatRun) {
+	if id == "" {
+		return
+	}
+	oc.runsMu.Lock()
+	if oc.runs == nil {
+		oc.runs = make(map[string]*chatRun)
+	}
+	oc.runs[id] = run
+	// Applied under the lock, so later events cannot overtake them.
+	if e := oc.early[id]; e != nil {
+		for _, ev := range e.events {
+			run.update(ev)
+		}
+		delete(oc.early, id)
+	}
+	oc.runsMu.Unlock()
+}
+
+func (oc *OpenClaw) untrackRun(id string) {
+	oc.runsMu.Lock()
+	defer oc.runsMu.Unlock()
+	delete(oc.runs, id)
+}
+
+// waitRun blocks until run ends, ctx is cancelled or the connection drops.
+// When not a single event of the run arrives within the quiet period, e.g.
+// because chat.subscribe failed, the reply is polled from the session JSONL
+// instead.
+func (oc *OpenClaw) waitRun(ctx context.Context, run *chatRun, done <-chan struct{}, sessionKey string, since time.Time) (string, error) {
+	quiet := oc.quiet
+	if quiet <= 0 {
+		quiet = time.Minute
+	}
+	timer := time.NewTimer(quiet)
+	defer timer.Stop()
+
+	seen, silent := run.seen, timer.C
+	for {
+		select {
+		case <-run.done:
+			return run.result()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-done:
+			return "", fmt.Errorf("%w while waiting for agent reply", errConnectionLost)
+		case <-seen:
+			seen, silent = nil, nil
+		case <-silent:
+			silent = nil
+			if oc.sessionsJSON == "" {
+				log.Printf("openclaw: no chat events for %s after %s and no session file to poll", sessionKey, quiet)
+				continue
+			}
+			log.Printf("openclaw: no chat events for %s after %s, polling session file", sessionKey, quiet)
+			return oc.pollRun(ctx, run, sessionKey, since)
+		}
+	}
+}
+
+// pollRun polls the session JSONL for the reply to run, preferring the run's
+// own result if its events turn up after all.
+func (oc *OpenClaw) pollRun(ctx context.Context, run *chatRun, sessionKey string, since time.Time) (string, error) {
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-run.done:
+			cancel()
+		case <-pctx.Done():
+		}
+	}()
+
+	reply, err := oc.pollReply(pctx, sessionKey, since)
+	select {
+	case <-run.done:
+		return run.result()
+	default:
+		return reply, err
 	}
 }
 
@@ -360,8 +710,9 @@ func (oc *OpenClaw) sendRequest(ctx context.Context, method string, params inter
 	oc.mu.Lock()
 	if oc.conn == nil {
 		oc.mu.Unlock()
-		return responseFrame{}, fmt.Errorf("not connected to gateway")
+		return responseFrame{}, errNotConnected
 	}
+	done := oc.done
 
 	id := oc.nextID()
 	req := requestFrame{
@@ -396,7 +747,7 @@ func (oc *OpenClaw) sendRequest(ctx context.Context, method string, params inter
 	select {
 	case resp, ok := <-ch:
 		if !ok {
-			return responseFrame{}, fmt.Errorf("connection closed while waiting for %s response", method)
+			return responseFrame{}, fmt.Errorf("%w while waiting for %s response", errConnectionLost, method)
 		}
 		return resp, nil
 	case <-ctx.Done():
@@ -404,13 +755,20 @@ func (oc *OpenClaw) sendRequest(ctx context.Context, method string, params inter
 		delete(oc.pending, id)
 		oc.pendMu.Unlock()
 		return responseFrame{}, ctx.Err()
-	case <-oc.done:
-		return responseFrame{}, fmt.Errorf("connection closed while waiting for %s response", method)
+	case <-done:
+		return responseFrame{}, fmt.Errorf("%w while waiting for %s response", errConnectionLost, method)
 	}
 }
 
-// SendAndReceive sends a message to the OpenClaw gateway and polls the
-// session JSONL until the agent produces a reply.
+// SendAndReceive sends a message to the OpenClaw gateway and waits for the
+// agent's reply. The reply comes from the run's chat events; gateways that
+// do not report a run ID for chat.send predate them, and the reply is then
+// polled from the session JSONL, which requires a gateway on this machine.
+//
+// While the connection is down, the call waits up to the reconnect grace
+// period for it to come back. If it drops while the call is in flight, the
+// message is sent again after the reconnect with the same idempotency key,
+// which the gateway deduplicates.
 func (oc *OpenClaw) SendAndReceive(ctx context.Context, req *Request) (string, error) {
 	// Format message with sender metadata — OpenClaw convention.
 	taggedText := fmt.Sprintf("From: %s (%s) [role: %s]\n%s",
@@ -421,11 +779,46 @@ func (oc *OpenClaw) SendAndReceive(ctx context.Context, req *Request) (string, e
 		sessionKey = oc.sessionKey
 	}
 
+	// The gateway uses the idempotency key as the run ID, so the run is
+	// tracked before sending and no early event is missed.
+	since := time.Now().UTC()
+	run := newChatRun()
+	oc.trackRun(req.IdempotencyKey, run)
+	defer oc.untrackRun(req.IdempotencyKey)
+
+	var err error
+	for attempt := 1; attempt <= maxSendAttempts; attempt++ {
+		if werr := oc.awaitConnected(ctx); werr != nil {
+			if err != nil {
+				return "", fmt.Errorf("%w; %v", err, werr)
+			}
+			return "", fmt.Errorf("chat.send: %w", werr)
+		}
+		var reply string
+		reply, err = oc.sendOnce(ctx, sessionKey, taggedText, req.IdempotencyKey, run, since)
+		if !isDisconnect(err) {
+			return reply, err
+		}
+		log.Printf("openclaw: %s: %v", req.IdempotencyKey, err)
+	}
+	return "", err
+}
+
+// sendOnce sends one chat.send over the current connection and waits for the
+// reply to it. since is when the message was first sent; replies older than
+// that belong to earlier messages.
+func (oc *OpenClaw) sendOnce(ctx context.Context, sessionKey, text, idempotencyKey string, run *chatRun, since time.Time) (string, error) {
+	oc.subscribe(ctx, sessionKey)
+
+	oc.mu.Lock()
+	connDone := oc.done
+	oc.mu.Unlock()
+
 	// Send message and wait for the gateway's acknowledgement.
 	resp, err := oc.sendRequest(ctx, "chat.send", chatSendParams{
 		SessionKey:     sessionKey,
-		Message:        taggedText,
-		IdempotencyKey: req.IdempotencyKey,
+		Message:        text,
+		IdempotencyKey: idempotencyKey,
 	})
 	if err != nil {
 		return "", fmt.Errorf("chat.send: %w", err)
@@ -434,33 +827,127 @@ func (oc *OpenClaw) SendAndReceive(ctx context.Context, req *Request) (string, e
 		return "", fmt.Errorf("chat.send rejected: %s", string(resp.Error))
 	}
 
-	// Poll session JSONL for the agent's reply.
-	return oc.pollReply(ctx, sessionKey)
+	var ack struct {
+		RunID   string `json:"runId"`
+		Status  string `json:"status"`
+		Summary string `json:"summary"`
+	}
+	_ = json.Unmarshal(resp.body(), &ack)
+	if ack.RunID == "" {
+		// Older gateway without chat events: poll session JSONL.
+		return oc.pollReply(ctx, sessionKey, since)
+	}
+	if ack.RunID != idempotencyKey {
+		oc.trackRun(ack.RunID, run)
+		defer oc.untrackRun(ack.RunID)
+	}
+
+	// A re-sent message whose run finished while the connection was down is
+	// answered from the gateway's dedup cache with the run's outcome. Its
+	// chat events are gone, so the reply is looked up instead.
+	select {
+	case <-run.done:
+		return run.result()
+	default:
+	}
+	switch ack.Status {
+	case "ok":
+		return oc.lookupReply(ctx, sessionKey, since)
+	case "error":
+		return "", fmt.Errorf("agent run %s failed: %s", ack.RunID, ack.Summary)
+	}
+	return oc.waitRun(ctx, run, connDone, sessionKey, since)
+}
+
+// lookupReply finds the reply to a run that has already finished: the latest
+// assistant message since then in the session history, or, when the gateway
+// cannot tell, in the session JSONL.
+func (oc *OpenClaw) lookupReply(ctx context.Context, sessionKey string, since time.Time) (string, error) {
+	reply, err := oc.historyReply(ctx, sessionKey, since)
+	if err == nil {
+		return reply, nil
+	}
+	if oc.sessionsJSON == "" {
+		return "", err
+	}
+	log.Printf("openclaw: %v; polling session file", err)
+	return oc.pollReply(ctx, sessionKey, since)
 }
 
-// pollReply polls the session JSONL file until an unclaimed assistant reply
-// appears. When session isolation produces a per-sender key that doesn't
-// exist in sessions.json, it falls back to the base session key.
-func (oc *OpenClaw) pollReply(ctx context.Context, sessionKey string) (string, error) {
-	since := time.Now().UTC()
-	deadline := time.Now().Add(10 * time.Minute)
-	ticker := time.NewTicker(3 * time.Second)
+// historyReply returns the latest assistant message of sessionKey written
+// after since, as reported by chat.history.
+func (oc *OpenClaw) historyReply(ctx context.Context, sessionKey string, since time.Time) (string, error) {
+	resp, err := oc.sendRequest(ctx, "chat.history", chatHistoryParams{SessionKey: sessionKey, Limit: historyLimit})
+	if err != nil {
+		return "", fmt.Errorf("chat.history: %w", err)
+	}
+	if resp.Error != nil {
+		return "", fmt.Errorf("chat.history rejected: %s", string(resp.Error))
+	}
+
+	var history struct {
+		Messages []struct {
+			chatMessage
+			Timestamp json.RawMessage `json:"timestamp"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(resp.body(), &history); err != nil {
+		return "", fmt.Errorf("parse chat.history: %w", err)
+	}
+	for i := len(history.Messages) - 1; i >= 0; i-- {
+		m := history.Messages[i]
+		t, ok := parseTimestamp(m.Timestamp)
+		if !ok {
+			continue // cannot tell whether it answers this message
+		}
+		if t.Before(since) {
+			break
+		}
+		if m.Role != "assistant" {
+			continue
+		}
+		if text := m.text(); text != "" {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("no reply in session %s history since %s", sessionKey, since.Format(time.RFC3339))
+}
+
+// parseTimestamp reads a history timestamp, sent either as a JSON number or
+// as a string, through kapso.ParseTimestamp.
+func parseTimestamp(raw json.RawMessage) (time.Time, bool) {
+	t := kapso.ParseTimestamp(strings.Trim(string(raw), `"`))
+	return t, !t.IsZero()
+}
+
+// pollReply waits for an unclaimed assistant reply written to the session
+// JSONL file after since. The file is followed by a tail shared with every other request on the
+// same session, so appended entries are parsed once. When session isolation
+// produces a per-sender key that doesn't exist in sessions.json, it falls
+// back to the base session key.
+func (oc *OpenClaw) pollReply(ctx context.Context, sessionKey string, since time.Time) (string, error) {
+	deadline := time.NewTimer(10 * time.Minute)
+	defer deadline.Stop()
+	interval := oc.pollInterval
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	useFallback := sessionKey != oc.sessionKey
 	loggedFallback := false
 
-	for {
-		if time.Now().After(deadline) {
-			return "", fmt.Errorf("timeout waiting for agent reply (session %s)", sessionKey)
-		}
-
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		case <-ticker.C:
+	var tail *sessionTail
+	defer func() {
+		if tail != nil {
+			oc.tails.release(tail)
 		}
+	}()
 
+	for {
+		// sessions.json is re-read on every tick: the session may not exist
+		// yet, or may be rotated to a new file.
 		sessionFile, err := getSessionFile(oc.sessionsJSON, sessionKey)
 		if err != nil && useFallback {
 			sessionFile, err = getSessionFile(oc.sessionsJSON, oc.sessionKey)
@@ -471,40 +958,62 @@ func (oc *OpenClaw) pollReply(ctx context.Context, sessionKey string) (string, e
 		}
 		if err != nil {
 			log.Printf("openclaw: %v", err)
-			continue
+		} else if tail == nil || tail.path != sessionFile {
+			if tail != nil {
+				oc.tails.release(tail)
+			}
+			tail = oc.tails.acquire(sessionFile, interval)
 		}
 
-		replies, err := getAssistantReplies(sessionFile, since)
-		if err != nil {
-			log.Printf("openclaw: error reading session: %v", err)
-			continue
-		}
+		for tick := false; !tick; {
+			var more <-chan struct{}
+			if tail != nil {
+				var replies []assistantReply
+				replies, more = tail.since(since)
+				for _, reply := range replies {
+					if oc.tracker.claim(reply.Key) {
+						return reply.Text, nil
+					}
+				}
+			}
 
-		for _, reply := range replies {
-			if oc.tracker.claim(reply.Key) {
-				return reply.Text, nil
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-deadline.C:
+				return "", fmt.Errorf("timeout waiting for agent reply (session %s)", sessionKey)
+			case <-more:
+			case <-ticker.C:
+				tick = true
 			}
 		}
 	}
 }
 
-// Close closes the WebSocket connection and waits for readLoop to exit.
+// Close closes the WebSocket connection, stops reconnecting, and waits for
+// readLoop and any reconnect in progress to exit.
 func (oc *OpenClaw) Close() error {
 	oc.mu.Lock()
-	if oc.conn == nil {
-		oc.mu.Unlock()
-		return nil
+	if oc.closing != nil {
+		select {
+		case <-oc.closing:
+		default:
+			close(oc.closing)
+		}
 	}
-	err := oc.conn.Close()
+	oc.setState(StateDisconnected)
+	conn, done := oc.conn, oc.done
 	oc.conn = nil
-	done := oc.done
 	oc.mu.Unlock()
 
-	// Wait for readLoop to finish cleanup. The wait is bounded because
-	// conn.Close() causes ReadMessage() to return an error immediately.
-	if done != nil {
+	var err error
+	if conn != nil {
+		err = conn.Close()
+		// Wait for readLoop to finish cleanup. The wait is bounded because
+		// conn.Close() causes ReadMessage() to return an error immediately.
 		<-done
 	}
+	oc.supervisor.Wait()
 	return err
 }
 
@@ -559,58 +1068,41 @@ func getSessionFile(sessionsJSON, sessionKey string) (string, error) {
 	return "", fmt.Errorf("no session file found for key %q in %s", sessionKey, sessionsJSON)
 }
 
-// getAssistantReplies scans the session JSONL for all assistant messages with
-// stopReason=stop that were recorded after `since`.
-func getAssistantReplies(sessionFile string, since time.Time) ([]assistantReply, error) {
-	data, err := os.ReadFile(sessionFile)
-	if err != nil {
-		return nil, err
+// parseAssistantLine parses one session JSONL line and reports whether it is
+// a finished assistant message (stopReason=stop) with text.
+func parseAssistantLine(line []byte) (time.Time, string, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return time.Time{}, "", false
 	}
 
-	var replies []assistantReply
-	for i, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var entry struct {
-			Type      string    `json:"type"`
-			Timestamp time.Time `json:"timestamp"`
-			Message   struct {
-				Role       string `json:"role"`
-				StopReason string `json:"stopReason"`
-				Content    []struct {
-					Type string `json:"type"`
-					Text string `json:"text"`
-				} `json:"content"`
-			} `json:"message"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
-
-		if entry.Type != "message" || entry.Timestamp.Before(since) {
-			continue
-		}
-		if entry.Message.Role != "assistant" || entry.Message.StopReason != "stop" {
-			continue
-		}
+	var entry struct {
+		Type      string    `json:"type"`
+		Timestamp time.Time `json:"timestamp"`
+		Message   struct {
+			Role       string `json:"role"`
+			StopReason string `json:"stopReason"`
+			Content    []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return time.Time{}, "", false
+	}
+	if entry.Type != "message" || entry.Message.Role != "assistant" || entry.Message.StopReason != "stop" {
+		return time.Time{}, "", false
+	}
 
-		var texts []string
-		for _, block := range entry.Message.Content {
-			if block.Type == "text" && block.Text != "" {
-				texts = append(texts, block.Text)
-			}
-		}
-		if len(texts) > 0 {
-			replies = append(replies, assistantReply{
-				Key:  fmt.Sprintf("%s:%d", sessionFile, i),
-				Text: strings.Join(texts, "\n"),
-			})
+	var texts []string
+	for _, block := range entry.Message.Content {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
 		}
 	}
-
-	return replies, nil
+	if len(texts) == 0 {
+		return time.Time{}, "", false
+	}
+	return entry.Timestamp, strings.Join(texts, "\n"), true
 }
@@ -512,7 +512,8 @@ func TestGetSessionFilePerSenderKeyFound(t *testing.T) {
 // ocTestServer creates a test WebSocket server that performs the OpenClaw
 // handshake and then calls handler for each subsequent request frame.
 // The handler receives the parsed request and returns a result or error to send.
-func ocTestServer(t *testing.T, handler func(req requestFrame) responseFrame) (*httptest.Server, string) {
+// Event frames passed to push are sent after the response, in order.
+func ocTestServer(t *testing.T, handler func(req requestFrame, push func(event string)) responseFrame) (*httptest.Server, string) {
 	t.Helper()
 	var upgrader = websocket.Upgrader{}
 	done := make(chan struct{})
@@ -562,13 +563,19 @@ func ocTestServer(t *testing.T, handler func(req requestFrame) responseFrame) (*
 				continue
 			}
 
-			respFrame := handler(req)
+			var events []string
+			respFrame := handler(req, func(event string) { events = append(events, event) })
 			respFrame.Type = "res"
 			respFrame.ID = req.ID
 			data, _ := json.Marshal(respFrame)
 			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 				return
 			}
+			for _, event := range events {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(event)); err != nil {
+					return
+				}
+			}
 		}
 	}))
 
@@ -584,7 +591,7 @@ func ocTestServer(t *testing.T, handler func(req requestFrame) responseFrame) (*
 // TestSendRequestRoutesResponseByID verifies that concurrent sendRequest
 // calls each receive their own response, matched by request ID.
 func TestSendRequestRoutesResponseByID(t *testing.T) {
-	_, wsURL := ocTestServer(t, func(req requestFrame) responseFrame {
+	_, wsURL := ocTestServer(t, func(req requestFrame, _ func(string)) responseFrame {
 		return responseFrame{
 			Result: json.RawMessage(fmt.Sprintf(`{"echo":%q}`, req.ID)),
 		}
@@ -630,7 +637,7 @@ func TestSendRequestRoutesResponseByID(t *testing.T) {
 // TestSendRequestReturnsErrorOnGatewayReject verifies that when the gateway
 // responds with an error frame, sendRequest returns it in the response.
 func TestSendRequestReturnsErrorOnGatewayReject(t *testing.T) {
-	_, wsURL := ocTestServer(t, func(req requestFrame) responseFrame {
+	_, wsURL := ocTestServer(t, func(req requestFrame, _ func(string)) responseFrame {
 		return responseFrame{
 			Error: json.RawMessage(`{"message":"session not found"}`),
 		}
@@ -705,7 +712,7 @@ func TestSendRequestUnblocksOnConnectionClose(t *testing.T) {
 // immediately with an error when the gateway rejects chat.send, without
 // entering the file-polling loop.
 func TestSendAndReceiveDetectsGatewayError(t *testing.T) {
-	_, wsURL := ocTestServer(t, func(req requestFrame) responseFrame {
+	_, wsURL := ocTestServer(t, func(req requestFrame, _ func(string)) responseFrame {
 		return responseFrame{
 			Error: json.RawMessage(`{"message":"unauthorized"}`),
 		}
@@ -994,13 +1001,14 @@ func TestBuildDeviceAuthPayloadV3(t *testing.T) {
 }
 
 // TestConcurrentClaimsUniqueReplies verifies that when multiple goroutines
-// race to read the same session JSONL file, each one claims a different
-// assistant reply.
+// race to read the same session JSONL file through its shared tail, each one
+// claims a different assistant reply.
 func TestConcurrentClaimsUniqueReplies(t *testing.T) {
 	dir := t.TempDir()
 	sessionFile := filepath.Join(dir, "session.jsonl")
 
-	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Recent enough to be within the tail's reply retention.
+	base := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
 
 	lines := ""
 	for i := 0; i < 3; i++ {
@@ -1016,6 +1024,7 @@ func TestConcurrentClaimsUniqueReplies(t *testing.T) {
 
 	since := base
 	tracker := newReplyTracker()
+	var tails sessionTails
 
 	const goroutines = 3
 	claimed := make([]string, goroutines)
@@ -1026,10 +1035,17 @@ func TestConcurrentClaimsUniqueReplies(t *testing.T) {
 		g := g
 		go func() {
 			defer wg.Done()
-			replies, err := getAssistantReplies(sessionFile, since)
-			if err != nil {
-				t.Errorf("goroutine %d: getAssistantReplies: %v", g, err)
-				return
+			tail := tails.acquire(sessionFile, time.Hour)
+			defer tails.release(tail)
+			replies, more := tail.since(since)
+			if len(replies) == 0 {
+				select {
+				case <-more:
+					replies, _ = tail.since(since)
+				case <-time.After(5 * time.Second):
+					t.Errorf("goroutine %d: tail read no replies", g)
+					return
+				}
 			}
 			for _, r := range replies {
 				if tracker.claim(r.Key) {
@@ -1061,3 +1077,408 @@ func TestConcurrentClaimsUniqueReplies(t *testing.T) {
 		t.Errorf("expected %d unique replies, got %d: %v", goroutines, len(seen), seen)
 	}
 }
+
+// chatSendKey returns the idempotency key of a chat.send request.
+func chatSendKey(req requestFrame) string {
+	data, _ := json.Marshal(req.Params)
+	var p chatSendParams
+	_ = json.Unmarshal(data, &p)
+	return p.IdempotencyKey
+}
+
+// TestSendAndReceiveUsesChatEvents verifies that the reply is taken from the
+// run's chat events, ignoring events of other runs.
+func TestSendAndReceiveUsesChatEvents(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	_, wsURL := ocTestServer(t, func(req requestFrame, push func(string)) responseFrame {
+		mu.Lock()
+		methods = append(methods, req.Method)
+		mu.Unlock()
+		switch req.Method {
+		case "chat.subscribe":
+			return responseFrame{Result: json.RawMessage(`{"ok":true}`)}
+		case "chat.send":
+			run := chatSendKey(req)
+			push(fmt.Sprintf(`{"type":"event","event":"chat","payload":{"runId":%q,"state":"delta","message":{"role":"assistant","content":[{"type":"text","text":"Hel"}]}}}`, run))
+			push(`{"type":"event","event":"chat","payload":{"runId":"other","state":"final","message":{"role":"assistant","content":"not yours"}}}`)
+			push(fmt.Sprintf(`{"type":"event","event":"chat","payload":{"runId":%q,"state":"final","message":{"role":"assistant","content":[{"type":"text","text":"Hello there"}]}}}`, run))
+			return responseFrame{Result: json.RawMessage(fmt.Sprintf(`{"runId":%q,"status":"started"}`, run))}
+		}
+		return responseFrame{}
+	})
+
+	client := newTestOpenClaw(wsURL, "test-token", nil)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, key := range []string{"wamid.1", "wamid.2"} {
+		reply, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: key, Text: "hi"})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if reply != "Hello there" {
+			t.Errorf("request %d: reply = %q, want %q", i, reply, "Hello there")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(methods) != 3 || methods[0] != "chat.subscribe" {
+		t.Errorf("methods = %v, want one chat.subscribe before two chat.send", methods)
+	}
+}
+
+// TestSendAndReceiveChatEventError verifies that a failed run is reported
+// as an error.
+func TestSendAndReceiveChatEventError(t *testing.T) {
+	_, wsURL := ocTestServer(t, func(req requestFrame, push func(string)) responseFrame {
+		switch req.Method {
+		case "chat.subscribe":
+			return responseFrame{Error: json.RawMessage(`{"message":"unknown method"}`)}
+		case "chat.send":
+			push(`{"type":"event","event":"chat","payload":{"runId":"run-7","state":"error","errorMessage":"model overloaded"}}`)
+			return responseFrame{Result: json.RawMessage(`{"runId":"run-7"}`)}
+		}
+		return responseFrame{}
+	})
+
+	client := newTestOpenClaw(wsURL, "test-token", nil)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Fatalf("expected run error, got %v", err)
+	}
+}
+
+// TestSendAndReceiveFallsBackToSessionPolling verifies that a gateway which
+// does not report a run ID gets its reply read from the session JSONL.
+func TestSendAndReceiveFallsBackToSessionPolling(t *testing.T) {
+	dir := t.TempDir()
+	sessionFile := filepath.Join(dir, "main.jsonl")
+	sessionsJSON := filepath.Join(dir, "sessions.json")
+	if err := os.WriteFile(sessionsJSON, []byte(fmt.Sprintf(`{"agent:main:main":{"sessionFile":%q}}`, sessionFile)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entry := fmt.Sprintf(`{"type":"message","timestamp":%q,"message":{"role":"assistant","stopReason":"stop","content":[{"type":"text","text":"from the file"}]}}`,
+		time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+	if err := os.WriteFile(sessionFile, []byte(entry+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, wsURL := ocTestServer(t, func(requestFrame, func(string)) responseFrame {
+		return responseFrame{Result: json.RawMessage(`{"ok":true}`)}
+	})
+
+	client := newTestOpenClaw(wsURL, "test-token", nil)
+	client.sessionsJSON = sessionsJSON
+	client.sessionKey = "main"
+	client.pollInterval = 10 * time.Millisecond
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "from the file" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+// TestSendAndReceivePollsWithoutChatEvents verifies that a run whose chat
+// events never arrive gets its reply from the session JSONL once the quiet
+// period has passed.
+func TestSendAndReceivePollsWithoutChatEvents(t *testing.T) {
+	dir := t.TempDir()
+	sessionFile := filepath.Join(dir, "main.jsonl")
+	sessionsJSON := filepath.Join(dir, "sessions.json")
+	if err := os.WriteFile(sessionsJSON, []byte(fmt.Sprintf(`{"agent:main:main":{"sessionFile":%q}}`, sessionFile)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entry := fmt.Sprintf(`{"type":"message","timestamp":%q,"message":{"role":"assistant","stopReason":"stop","content":[{"type":"text","text":"from the file"}]}}`,
+		time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+	if err := os.WriteFile(sessionFile, []byte(entry+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, wsURL := ocTestServer(t, func(req requestFrame, _ func(string)) responseFrame {
+		switch req.Method {
+		case "chat.subscribe":
+			return responseFrame{Error: json.RawMessage(`{"message":"unknown method"}`)}
+		case "chat.send":
+			return responseFrame{Result: json.RawMessage(fmt.Sprintf(`{"runId":%q,"status":"started"}`, chatSendKey(req)))}
+		}
+		return responseFrame{}
+	})
+
+	client := newTestOpenClaw(wsURL, "test-token", nil)
+	client.sessionsJSON = sessionsJSON
+	client.sessionKey = "main"
+	client.pollInterval = 10 * time.Millisecond
+	client.quiet = 50 * time.Millisecond
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "from the file" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+// TestKeepEarlyExpiresByAge verifies that events of untracked runs expire
+// one run at a time instead of all at once.
+func TestKeepEarlyExpiresByAge(t *testing.T) {
+	oc := &OpenClaw{}
+	base := time.Unix(1700000000, 0)
+
+	oc.keepEarly(chatEvent{RunID: "old"}, base)
+	oc.keepEarly(chatEvent{RunID: "recent"}, base.Add(earlyRetention/2))
+	oc.keepEarly(chatEvent{RunID: "new"}, base.Add(earlyRetention))
+	if _, ok := oc.early["old"]; ok {
+		t.Error("expired run kept")
+	}
+	if _, ok := oc.early["recent"]; !ok {
+		t.Error("recent run dropped with the expired one")
+	}
+
+	// At the cap, only the oldest run makes room.
+	for i := len(oc.early); i < maxEarlyRuns; i++ {
+		oc.keepEarly(chatEvent{RunID: fmt.Sprintf("run-%d", i)}, base.Add(earlyRetention))
+	}
+	oc.keepEarly(chatEvent{RunID: "one-more"}, base.Add(earlyRetention))
+	if len(oc.early) != maxEarlyRuns {
+		t.Errorf("kept %d runs, want %d", len(oc.early), maxEarlyRuns)
+	}
+	if _, ok := oc.early["recent"]; ok {
+		t.Error("oldest run kept past the cap")
+	}
+	if _, ok := oc.early["new"]; !ok {
+		t.Error("newer run dropped instead of the oldest")
+	}
+}
+
+// TestSendAndReceiveResendsAfterReconnect verifies that a dropped connection
+// is re-established with a new handshake and that the in-flight message is
+// sent again with the same idempotency key.
+func TestSendAndReceiveResendsAfterReconnect(t *testing.T) {
+	var upgrader = websocket.Upgrader{}
+	var mu sync.Mutex
+	var conns int
+	var sends []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		mu.Lock()
+		conns++
+		n := conns
+		mu.Unlock()
+
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"event","method":"challenge"}`))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var connectReq requestFrame
+		_ = json.Unmarshal(msg, &connectReq)
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":{"ok":true}}`, connectReq.ID)))
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req requestFrame
+			_ = json.Unmarshal(msg, &req)
+			switch req.Method {
+			case "chat.subscribe":
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":{"ok":true}}`, req.ID)))
+			case "chat.send":
+				run := chatSendKey(req)
+				mu.Lock()
+				sends = append(sends, run)
+				mu.Unlock()
+				if n == 1 {
+					return // drop the connection mid-request
+				}
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":{"runId":%q}}`, req.ID, run)))
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"event","event":"chat","payload":{"runId":%q,"state":"final","message":{"role":"assistant","content":"back again"}}}`, run)))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestOpenClaw("ws"+strings.TrimPrefix(srv.URL, "http"), "test-token", nil)
+	client.backoff = 10 * time.Millisecond
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "back again" {
+		t.Errorf("reply = %q", reply)
+	}
+	if s := client.State(); s != StateConnected {
+		t.Errorf("State = %s, want %s", s, StateConnected)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 2 {
+		t.Errorf("connections = %d, want 2", conns)
+	}
+	if len(sends) != 2 || sends[0] != "wamid.1" || sends[1] != "wamid.1" {
+		t.Errorf("chat.send keys = %v, want wamid.1 twice", sends)
+	}
+}
+
+// TestSendAndReceiveRunFinishedDuringOutage verifies that a re-sent message
+// whose run finished while the connection was down gets its reply from the
+// session history, since the run's chat events are not sent again.
+func TestSendAndReceiveRunFinishedDuringOutage(t *testing.T) {
+	var upgrader = websocket.Upgrader{}
+	var mu sync.Mutex
+	var conns int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		mu.Lock()
+		conns++
+		n := conns
+		mu.Unlock()
+
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"event","method":"challenge"}`))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var connectReq requestFrame
+		_ = json.Unmarshal(msg, &connectReq)
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":{"ok":true}}`, connectReq.ID)))
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req requestFrame
+			_ = json.Unmarshal(msg, &req)
+			var resp string
+			switch req.Method {
+			case "chat.subscribe":
+				resp = `{"ok":true}`
+			case "chat.send":
+				if n == 1 {
+					return // the run finishes while the connection is down
+				}
+				resp = fmt.Sprintf(`{"runId":%q,"status":"ok"}`, chatSendKey(req))
+			case "chat.history":
+				old := time.Now().Add(-time.Hour).UnixMilli()
+				now := time.Now().Add(time.Second).UnixMilli()
+				resp = fmt.Sprintf(`{"messages":[`+
+					`{"role":"assistant","content":"earlier reply","timestamp":%d},`+
+					`{"role":"user","content":"hi","timestamp":%d},`+
+					`{"role":"assistant","content":[{"type":"text","text":"done while you were away"}],"timestamp":%d}]}`, old, now, now)
+			}
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":%s}`, req.ID, resp)))
+		}
+	}))
+	defer srv.Close()
+
+	client := newTestOpenClaw("ws"+strings.TrimPrefix(srv.URL, "http"), "test-token", nil)
+	client.backoff = 10 * time.Millisecond
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.SendAndReceive(ctx, &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "done while you were away" {
+		t.Errorf("reply = %q", reply)
+	}
+}
+
+// TestSendAndReceiveGivesUpAfterGrace verifies that messages fail once the
+// gateway stays unreachable for the grace period, and that Close stops the
+// reconnect attempts.
+func TestSendAndReceiveGivesUpAfterGrace(t *testing.T) {
+	var upgrader = websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"event","method":"challenge"}`))
+		_, msg, _ := conn.ReadMessage()
+		var req requestFrame
+		_ = json.Unmarshal(msg, &req)
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"res","id":%q,"result":{"ok":true}}`, req.ID)))
+		_ = conn.Close()
+	}))
+
+	client := newTestOpenClaw("ws"+strings.TrimPrefix(srv.URL, "http"), "test-token", nil)
+	client.backoff = 10 * time.Millisecond
+	client.grace = 100 * time.Millisecond
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	srv.Close() // every reconnect attempt fails from now on
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.State() != StateReconnecting && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := client.State(); s != StateReconnecting {
+		t.Fatalf("State = %s, want %s", s, StateReconnecting)
+	}
+
+	_, err := client.SendAndReceive(context.Background(), &Request{SessionKey: "main", IdempotencyKey: "wamid.1", Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "did not reconnect") {
+		t.Errorf("expected a reconnect timeout, got %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if s := client.State(); s != StateDisconnected {
+		t.Errorf("State after Close = %s, want %s", s, StateDisconnected)
+	}
+}
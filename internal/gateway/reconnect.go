@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// maxSendAttempts caps how often SendAndReceive sends one message when the
+// connection keeps dropping.
+const maxSendAttempts = 3
+
+var (
+	errNotConnected   = errors.New("not connected to gateway")
+	errConnectionLost = errors.New("connection closed")
+)
+
+// isDisconnect reports whether err means the connection was down or dropped,
+// as opposed to the gateway answering with an error.
+func isDisconnect(err error) bool {
+	return errors.Is(err, errNotConnected) || errors.Is(err, errConnectionLost)
+}
+
+// State reports the state of the gateway connection.
+func (oc *OpenClaw) State() ConnState {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if oc.state == "" {
+		return StateDisconnected
+	}
+	return oc.state
+}
+
+// setState records and logs a connection state change. Caller must hold mu.
+func (oc *OpenClaw) setState(s ConnState) {
+	if oc.state == s {
+		return
+	}
+	if oc.state != "" || s != StateConnected {
+		log.Printf("openclaw: connection %s", s)
+	}
+	oc.state = s
+}
+
+// reconnect re-establishes a dropped connection with exponential backoff,
+// rerunning the full challenge/device-signature handshake on each attempt,
+// until it succeeds or closing is closed.
+func (oc *OpenClaw) reconnect(closing <-chan struct{}) {
+	defer oc.supervisor.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	delay := oc.backoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	maxDelay := oc.maxBackoff
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+
+	for attempt := 1; ; attempt++ {
+		wait := delay + time.Duration(float64(delay)*0.25*rand.Float64()) //nolint:gosec
+		log.Printf("openclaw: reconnecting to %s in %s (attempt %d)", oc.url, wait.Round(time.Millisecond), attempt)
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		conn, err := oc.handshake(ctx)
+		if err != nil {
+			log.Printf("openclaw: reconnect attempt %d failed: %v", attempt, err)
+			delay = min(delay*2, maxDelay)
+			continue
+		}
+
+		oc.mu.Lock()
+		if ctx.Err() != nil {
+			oc.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		oc.attach(conn)
+		oc.mu.Unlock()
+
+		// Subscriptions belong to the old connection.
+		oc.runsMu.Lock()
+		oc.subscribed = nil
+		oc.runsMu.Unlock()
+
+		log.Printf("openclaw: reconnected to %s after %d attempt(s)", oc.url, attempt)
+		return
+	}
+}
+
+// awaitConnected returns once the connection is up, waiting up to the
+// reconnect grace period while it is being re-established.
+func (oc *OpenClaw) awaitConnected(ctx context.Context) error {
+	oc.mu.Lock()
+	up, closing := oc.up, oc.closing
+	oc.mu.Unlock()
+	if up == nil {
+		return errNotConnected
+	}
+	select {
+	case <-closing:
+		return errNotConnected
+	case <-up:
+		return nil
+	default:
+	}
+
+	grace := oc.grace
+	if grace <= 0 {
+		grace = 30 * time.Second
+	}
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	select {
+	case <-up:
+		return nil
+	case <-closing:
+		return errNotConnected
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return fmt.Errorf("gateway did not reconnect within %s: %w", grace, errNotConnected)
+	}
+}
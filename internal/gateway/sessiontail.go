@@ -0,0 +1,229 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// replyRetention is how long tailed assistant replies are kept for waiters;
+// it matches the longest wait in pollReply.
+const replyRetention = 10 * time.Minute
+
+// errWatchUnsupported is returned by watchFile where file notifications are
+// not available; tails then poll.
+var errWatchUnsupported = errors.New("file watching not supported")
+
+// sessionTail follows one session JSONL file. It remembers how far the file
+// was read, so each wake-up reads only appended bytes, and it is shared by
+// every request waiting on the same file. It wakes on file notifications
+// where available and polls otherwise. It only runs while someone waits, but
+// keeps its offset in between.
+type sessionTail struct {
+	path     string
+	interval time.Duration // poll interval without notifications
+
+	mu      sync.Mutex
+	file    os.FileInfo // identity of the file read so far
+	offset  int64
+	line    int    // index of the next line, for reply keys
+	partial []byte // trailing bytes of an unfinished line
+	replies []tailedReply
+	notify  chan struct{} // closed and replaced when replies are added
+
+	// Guarded by sessionTails.mu.
+	refs     int
+	stop     chan struct{}
+	done     chan struct{}
+	released time.Time // when the last waiter left
+}
+
+type tailedReply struct {
+	assistantReply
+	Time time.Time
+}
+
+func newSessionTail(path string, interval time.Duration) *sessionTail {
+	return &sessionTail{
+		path:     path,
+		interval: interval,
+		notify:   make(chan struct{}),
+	}
+}
+
+// run reads the file once, then again on every wake-up until stop is closed.
+func (t *sessionTail) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	t.read()
+
+	interval := t.interval
+	events, closeWatch, err := watchFile(t.path)
+	if err == nil {
+		defer closeWatch()
+		// Notifications can be missed (e.g. the directory is replaced), so
+		// a slow poll stays on as a safety net.
+		interval *= 10
+	} else if !errors.Is(err, errWatchUnsupported) {
+		log.Printf("openclaw: watching %s failed, polling instead: %v", t.path, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-events:
+		case <-ticker.C:
+		}
+		t.read()
+	}
+}
+
+// read consumes what was appended since the last read. A file that shrank or
+// was replaced is read again from the start.
+func (t *sessionTail) read() {
+	f, err := os.Open(t.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("openclaw: error reading session: %v", err)
+		}
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		log.Printf("openclaw: error reading session: %v", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil || !os.SameFile(t.file, info) || info.Size() < t.offset {
+		t.offset, t.line, t.partial = 0, 0, nil
+	}
+	t.file = info
+	if info.Size() == t.offset {
+		return
+	}
+
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		log.Printf("openclaw: error reading session: %v", err)
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		log.Printf("openclaw: error reading session: %v", err)
+		return
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	cutoff := time.Now().Add(-replyRetention)
+	added := false
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		ts, text, ok := parseAssistantLine(data[:i])
+		if ok && !ts.Before(cutoff) {
+			t.replies = append(t.replies, tailedReply{
+				assistantReply: assistantReply{Key: fmt.Sprintf("%s:%d", t.path, t.line), Text: text},
+				Time:           ts,
+			})
+			added = true
+		}
+		t.line++
+		data = data[i+1:]
+	}
+	t.partial = append([]byte(nil), data...)
+
+	// Forget replies no waiter can still be interested in.
+	kept := t.replies[:0]
+	for _, r := range t.replies {
+		if !r.Time.Before(cutoff) {
+			kept = append(kept, r)
+		}
+	}
+	t.replies = kept
+
+	if added {
+		close(t.notify)
+		t.notify = make(chan struct{})
+	}
+}
+
+// since returns the replies recorded at or after since, and a channel that is
+// closed when more arrive.
+func (t *sessionTail) since(since time.Time) ([]assistantReply, <-chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []assistantReply
+	for _, r := range t.replies {
+		if !r.Time.Before(since) {
+			out = append(out, r.assistantReply)
+		}
+	}
+	return out, t.notify
+}
+
+// sessionTails shares one sessionTail per file between waiters. Tails are
+// kept after their last waiter leaves, so the next request on the same
+// session resumes from the remembered offset, and dropped once they have
+// been idle for replyRetention, when their replies are stale anyway.
+type sessionTails struct {
+	mu    sync.Mutex
+	tails map[string]*sessionTail
+}
+
+// acquire returns the tail of path, starting it for the first waiter.
+func (st *sessionTails) acquire(path string, interval time.Duration) *sessionTail {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tails == nil {
+		st.tails = make(map[string]*sessionTail)
+	}
+	now := time.Now()
+	for p, t := range st.tails {
+		if t.refs == 0 && now.Sub(t.released) >= replyRetention {
+			delete(st.tails, p)
+		}
+	}
+	t := st.tails[path]
+	if t == nil {
+		t = newSessionTail(path, interval)
+		st.tails[path] = t
+	}
+	if t.refs == 0 {
+		t.stop, t.done = make(chan struct{}), make(chan struct{})
+		go t.run(t.stop, t.done)
+	}
+	t.refs++
+	return t
+}
+
+// release stops the tail's reader once its last waiter is gone.
+func (st *sessionTails) release(t *sessionTail) {
+	st.mu.Lock()
+	t.refs--
+	var stop, done chan struct{}
+	if t.refs == 0 {
+		stop, done = t.stop, t.done
+		t.released = time.Now()
+	}
+	st.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func assistantLine(ts time.Time, text string) string {
+	return fmt.Sprintf(`{"type":"message","timestamp":%q,"message":{"role":"assistant","stopReason":"stop","content":[{"type":"text","text":%q}]}}`,
+		ts.UTC().Format(time.RFC3339Nano), text) + "\n"
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSessionTailReadsIncrementally verifies that appended entries are picked
+// up without re-reading earlier ones, including a line written in two parts.
+func TestSessionTailReadsIncrementally(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	now := time.Now()
+	appendFile(t, path, assistantLine(now.Add(-time.Hour), "too old")+assistantLine(now, "first"))
+
+	tail := newSessionTail(path, time.Hour)
+	tail.read()
+	replies, _ := tail.since(now.Add(-time.Second))
+	if len(replies) != 1 || replies[0].Text != "first" {
+		t.Fatalf("replies = %+v, want only the recent one", replies)
+	}
+
+	line := assistantLine(now.Add(time.Second), "second")
+	appendFile(t, path, line[:20])
+	tail.read()
+	appendFile(t, path, line[20:])
+	tail.read()
+
+	replies, _ = tail.since(now.Add(-time.Second))
+	if len(replies) != 2 || replies[1].Text != "second" {
+		t.Fatalf("replies = %+v, want first and second", replies)
+	}
+	// Keys are the file and line index, so they stay stable across reads.
+	if replies[0].Key != path+":1" || replies[1].Key != path+":2" {
+		t.Errorf("keys = %q, %q, want %s:1 and %s:2", replies[0].Key, replies[1].Key, path, path)
+	}
+}
+
+// TestSessionTailRestartsOnTruncation verifies that a file that shrinks is
+// read again from the start.
+func TestSessionTailRestartsOnTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	now := time.Now()
+	appendFile(t, path, assistantLine(now, "a long reply before the reset"))
+
+	tail := newSessionTail(path, time.Hour)
+	tail.read()
+
+	if err := os.WriteFile(path, []byte(assistantLine(now.Add(time.Second), "new")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tail.read()
+
+	replies, _ := tail.since(now.Add(500 * time.Millisecond))
+	if len(replies) != 1 || replies[0].Text != "new" {
+		t.Fatalf("replies = %+v, want the entry of the new file", replies)
+	}
+}
+
+// TestSessionTailsShareOneReader verifies that waiters on the same file get
+// one tail, are woken when a reply is appended, and that the reader resumes
+// from its offset after the last waiter leaves.
+func TestSessionTailsShareOneReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	appendFile(t, path, "")
+
+	var tails sessionTails
+	a := tails.acquire(path, 10*time.Millisecond)
+	b := tails.acquire(path, 10*time.Millisecond)
+	if a != b {
+		t.Fatal("acquire returned different tails for the same file")
+	}
+
+	since := time.Now().Add(-time.Second)
+	_, more := a.since(since)
+	appendFile(t, path, assistantLine(time.Now(), "hello"))
+
+	select {
+	case <-more:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter was not woken by the appended reply")
+	}
+	if replies, _ := b.since(since); len(replies) != 1 || replies[0].Text != "hello" {
+		t.Fatalf("second waiter saw %+v", replies)
+	}
+
+	tails.release(a)
+	tails.release(b)
+	select {
+	case <-b.done:
+	default:
+		t.Fatal("reader still running after the last release")
+	}
+
+	offset := b.offset
+	c := tails.acquire(path, 10*time.Millisecond)
+	defer tails.release(c)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c != a || c.offset != offset {
+		t.Errorf("re-acquired tail starts at %d, want the kept offset %d", c.offset, offset)
+	}
+}
+
+// TestSessionTailsDropIdleTails verifies that a tail nobody has waited on for
+// replyRetention is removed, while a tail in use is kept.
+func TestSessionTailsDropIdleTails(t *testing.T) {
+	dir := t.TempDir()
+	idlePath := filepath.Join(dir, "idle.jsonl")
+	busyPath := filepath.Join(dir, "busy.jsonl")
+
+	var tails sessionTails
+	tails.release(tails.acquire(idlePath, 10*time.Millisecond))
+	busy := tails.acquire(busyPath, 10*time.Millisecond)
+	defer tails.release(busy)
+
+	tails.mu.Lock()
+	tails.tails[idlePath].released = time.Now().Add(-replyRetention)
+	tails.mu.Unlock()
+
+	other := tails.acquire(filepath.Join(dir, "other.jsonl"), 10*time.Millisecond)
+	defer tails.release(other)
+
+	tails.mu.Lock()
+	defer tails.mu.Unlock()
+	if _, ok := tails.tails[idlePath]; ok {
+		t.Error("idle tail kept")
+	}
+	if _, ok := tails.tails[busyPath]; !ok {
+		t.Error("tail in use dropped")
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchFile reports changes to path through inotify. The parent directory is
+// watched, so the file may be created, replaced or rotated. Each receive on
+// the channel means path may have changed; the returned function stops
+// watching.
+func watchFile(path string) (<-chan struct{}, func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inotify init: %w", err)
+	}
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	mask := uint32(syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_DELETE)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		_ = syscall.Close(fd)
+		return nil, nil, fmt.Errorf("inotify watch %s: %w", dir, err)
+	}
+
+	// A non-blocking descriptor in an *os.File goes through the runtime
+	// poller, so Close unblocks a pending Read.
+	f := os.NewFile(uintptr(fd), "inotify")
+	events := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			if touches(buf[:n], name) {
+				select {
+				case events <- struct{}{}:
+				default: // a wake-up is already pending
+				}
+			}
+		}
+	}()
+	return events, func() { _ = f.Close() }, nil
+}
+
+// touches reports whether a buffer of inotify events mentions name.
+func touches(buf []byte, name string) bool {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := syscall.SizeofInotifyEvent + int(ev.Len)
+		if end > len(buf) {
+			return false
+		}
+		evName := bytes.TrimRight(buf[syscall.SizeofInotifyEvent:end], "\x00")
+		if string(evName) == name {
+			return true
+		}
+		buf = buf[end:]
+	}
+	return false
+}
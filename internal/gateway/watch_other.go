@@ -0,0 +1,8 @@
+//go:build !linux
+
+package gateway
+
+// watchFile is not implemented on this platform; session tails poll.
+func watchFile(string) (<-chan struct{}, func(), error) {
+	return nil, nil, errWatchUnsupported
+}
@@ -0,0 +1,111 @@
+// Package history keeps a bounded record of WhatsApp messages the bridge has
+// received or sent, keyed by message ID. It lets the bridge resolve the
+// message a user quoted when they swipe to reply.
+package history
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity bounds the number of remembered messages.
+const DefaultCapacity = 5000
+
+// maxQuoteLen bounds the quoted text forwarded to the agent, in runes.
+const maxQuoteLen = 300
+
+// Entry is a single remembered message.
+type Entry struct {
+	ID       string    // WhatsApp message ID
+	Peer     string    // the user's phone number (sender or recipient)
+	Text     string    // gateway-ready text (inbound) or sent text (outbound)
+	Outbound bool      // true when the bridge sent the message
+	Resend   bool      // true for a re-send of a failed outbound message
+	Time     time.Time // when the bridge recorded it
+}
+
+// Store is a fixed-capacity, concurrency-safe message record. When full, the
+// oldest entry is evicted first.
+type Store struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]Entry
+	order    []string // insertion order, oldest first
+	now      func() time.Time
+}
+
+// New creates a Store holding at most capacity entries (DefaultCapacity if <= 0).
+func New(capacity int) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{
+		capacity: capacity,
+		entries:  make(map[string]Entry),
+		now:      time.Now,
+	}
+}
+
+// Record remembers e. Entries without an ID are ignored; recording an
+// existing ID replaces it without changing its eviction order.
+func (s *Store) Record(e Entry) {
+	if e.ID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+	if _, exists := s.entries[e.ID]; !exists {
+		if len(s.order) >= s.capacity {
+			oldest := s.order[0]
+			s.order = s.order[1:]
+			delete(s.entries, oldest)
+		}
+		s.order = append(s.order, e.ID)
+	}
+	s.entries[e.ID] = e
+}
+
+// Get returns the entry for id, if it is still remembered.
+func (s *Store) Get(id string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	return e, ok
+}
+
+// Len returns the number of remembered messages.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Quote describes the message with the given ID for the agent, e.g.
+// `[in reply to assistant: "Tuesday or Wednesday?"]`. It returns "" for an
+// empty ID and a generic marker when the message is no longer remembered.
+func (s *Store) Quote(id string) string {
+	if id == "" {
+		return ""
+	}
+	e, ok := s.Get(id)
+	if !ok || e.Text == "" {
+		return "[in reply to an earlier message]"
+	}
+
+	who := "user"
+	if e.Outbound {
+		who = "assistant"
+	}
+	text := strings.Join(strings.Fields(e.Text), " ")
+	if r := []rune(text); len(r) > maxQuoteLen {
+		text = string(r[:maxQuoteLen]) + "…"
+	}
+	return fmt.Sprintf("[in reply to %s: %q]", who, text)
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndGet(t *testing.T) {
+	s := New(10)
+	s.Record(Entry{ID: "wamid.1", Peer: "+1", Text: "hello"})
+	s.Record(Entry{ID: "wamid.2", Peer: "+1", Text: "hi there", Outbound: true})
+
+	e, ok := s.Get("wamid.2")
+	if !ok {
+		t.Fatal("expected wamid.2 to be recorded")
+	}
+	if e.Text != "hi there" || !e.Outbound {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Time.IsZero() {
+		t.Error("Record should stamp the entry time")
+	}
+	if _, ok := s.Get("wamid.missing"); ok {
+		t.Error("unexpected entry for unknown ID")
+	}
+}
+
+func TestRecordIgnoresEmptyID(t *testing.T) {
+	s := New(10)
+	s.Record(Entry{Text: "no id"})
+	if s.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", s.Len())
+	}
+}
+
+func TestEvictsOldestWhenFull(t *testing.T) {
+	s := New(3)
+	for i := 1; i <= 4; i++ {
+		s.Record(Entry{ID: fmt.Sprintf("m%d", i)})
+	}
+	if s.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", s.Len())
+	}
+	if _, ok := s.Get("m1"); ok {
+		t.Error("m1 should have been evicted")
+	}
+	if _, ok := s.Get("m4"); !ok {
+		t.Error("m4 should be present")
+	}
+}
+
+func TestRecordReplacesExisting(t *testing.T) {
+	s := New(2)
+	s.Record(Entry{ID: "m1", Text: "old"})
+	s.Record(Entry{ID: "m1", Text: "new"})
+	s.Record(Entry{ID: "m2"})
+
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+	if e, _ := s.Get("m1"); e.Text != "new" {
+		t.Errorf("m1 text = %q, want %q", e.Text, "new")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	s := New(10)
+	s.Record(Entry{ID: "out", Text: "Tuesday or\nWednesday?", Outbound: true})
+	s.Record(Entry{ID: "in", Text: "book me in"})
+	s.Record(Entry{ID: "long", Text: strings.Repeat("a", maxQuoteLen+10)})
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"", ""},
+		{"out", `[in reply to assistant: "Tuesday or Wednesday?"]`},
+		{"in", `[in reply to user: "book me in"]`},
+		{"gone", "[in reply to an earlier message]"},
+		{"long", `[in reply to user: "` + strings.Repeat("a", maxQuoteLen) + `…"]`},
+	}
+	for _, tt := range tests {
+		if got := s.Quote(tt.id); got != tt.want {
+			t.Errorf("Quote(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
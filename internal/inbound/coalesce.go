@@ -0,0 +1,123 @@
+package inbound
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// Coalescer debounces bursts of messages per sender: events are held until
+// the sender has been quiet for Window (or MaxWait has passed since the
+// first one) and then handed to Flush together. Senders are identified by
+// Key, so messages to different business numbers are never merged.
+type Coalescer struct {
+	Window  time.Duration // quiet period that ends a burst; <= 0 = flush every event at once
+	MaxWait time.Duration // upper bound on how long a burst is held; 0 = no bound
+
+	// Flush receives each burst with its sender key, oldest event first. It
+	// is called with the Coalescer's lock held, which keeps bursts of one
+	// sender in order, so it must not block or call back into the Coalescer.
+	Flush func(sender string, events []delivery.Event)
+
+	mu      sync.Mutex
+	pending map[string]*burst
+	now     func() time.Time
+}
+
+// burst is the events held for one sender.
+type burst struct {
+	events []delivery.Event
+	first  time.Time
+	timer  *time.Timer
+}
+
+// Add holds evt until its sender's burst ends.
+func (c *Coalescer) Add(evt delivery.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Window <= 0 {
+		c.Flush(Key(evt), []delivery.Event{evt})
+		return
+	}
+	if c.pending == nil {
+		c.pending = make(map[string]*burst)
+	}
+
+	now := c.clock()
+	sender := Key(evt)
+	b := c.pending[sender]
+	if b == nil {
+		b = &burst{first: now}
+		c.pending[sender] = b
+	} else if !b.timer.Stop() {
+		// The timer already fired and is waiting for the lock; it will
+		// flush this event along with the rest of the burst.
+		b.events = append(b.events, evt)
+		return
+	}
+	b.events = append(b.events, evt)
+
+	delay := c.Window
+	if c.MaxWait > 0 {
+		if left := b.first.Add(c.MaxWait).Sub(now); left < delay {
+			delay = max(left, 0)
+		}
+	}
+	b.timer = time.AfterFunc(delay, func() { c.fire(sender, b) })
+}
+
+// FlushSender hands any held events of the sender key to Flush immediately,
+// e.g. before a bridge command from the same sender is processed.
+func (c *Coalescer) FlushSender(sender string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.pending[sender]
+	if b == nil {
+		return
+	}
+	b.timer.Stop()
+	delete(c.pending, sender)
+	c.Flush(sender, b.events)
+}
+
+// Pending returns the number of events currently held.
+func (c *Coalescer) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, b := range c.pending {
+		n += len(b.events)
+	}
+	return n
+}
+
+func (c *Coalescer) fire(sender string, b *burst) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// FlushSender got there first.
+	if c.pending[sender] != b {
+		return
+	}
+	delete(c.pending, sender)
+	c.Flush(sender, b.events)
+}
+
+// Key identifies the sender of evt for per-sender ordering: the sender's
+// phone, qualified by the receiving business number when it is known.
+func Key(evt delivery.Event) string {
+	if evt.PhoneNumberID == "" {
+		return evt.From
+	}
+	return evt.PhoneNumberID + "/" + evt.From
+}
+
+func (c *Coalescer) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,154 @@
+package inbound
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// recorder collects flushed bursts.
+type recorder struct {
+	mu     sync.Mutex
+	bursts [][]string
+	done   chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{done: make(chan struct{}, 16)}
+}
+
+func (r *recorder) flush(_ string, events []delivery.Event) {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	r.mu.Lock()
+	r.bursts = append(r.bursts, ids)
+	r.mu.Unlock()
+	r.done <- struct{}{}
+}
+
+func (r *recorder) wait(t *testing.T, n int) [][]string {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for burst %d", i+1)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bursts
+}
+
+func TestCoalescer_MergesBurst(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Window: 50 * time.Millisecond, Flush: r.flush}
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		c.Add(delivery.Event{ID: id, From: "+1555"})
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Add(delivery.Event{ID: "x1", From: "+1666"})
+
+	bursts := r.wait(t, 2)
+	var got []string
+	for _, b := range bursts {
+		if len(b) == 3 {
+			got = b
+		}
+	}
+	if len(got) != 3 || got[0] != "m1" || got[2] != "m3" {
+		t.Fatalf("bursts = %v, want [m1 m2 m3] merged", bursts)
+	}
+	if c.Pending() != 0 {
+		t.Errorf("Pending = %d after flush", c.Pending())
+	}
+}
+
+func TestCoalescer_QuietPeriodSplitsBursts(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Window: 20 * time.Millisecond, Flush: r.flush}
+
+	c.Add(delivery.Event{ID: "m1", From: "+1555"})
+	r.wait(t, 1)
+	c.Add(delivery.Event{ID: "m2", From: "+1555"})
+
+	bursts := r.wait(t, 1)
+	if len(bursts) != 2 || bursts[0][0] != "m1" || bursts[1][0] != "m2" {
+		t.Fatalf("bursts = %v, want [[m1] [m2]]", bursts)
+	}
+}
+
+func TestCoalescer_MaxWait(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Window: 40 * time.Millisecond, MaxWait: 60 * time.Millisecond, Flush: r.flush}
+
+	// A steady stream, each event inside the window, must still be flushed
+	// around MaxWait.
+	start := time.Now()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+				t.Errorf("first burst flushed after %v, want about MaxWait", elapsed)
+			}
+			return
+		case <-ticker.C:
+			if time.Since(start) > time.Second {
+				t.Fatal("stream never flushed")
+			}
+			c.Add(delivery.Event{ID: "m", From: "+1555"})
+		}
+	}
+}
+
+func TestCoalescer_FlushSender(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Window: time.Hour, Flush: r.flush}
+
+	c.Add(delivery.Event{ID: "m1", From: "+1555"})
+	c.Add(delivery.Event{ID: "m2", From: "+1555"})
+	c.FlushSender("+1555")
+	c.FlushSender("+1555") // nothing left
+
+	bursts := r.wait(t, 1)
+	if len(bursts) != 1 || len(bursts[0]) != 2 {
+		t.Fatalf("bursts = %v, want one burst of 2", bursts)
+	}
+}
+
+func TestCoalescer_KeyedByNumber(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Window: time.Hour, Flush: r.flush}
+
+	c.Add(delivery.Event{ID: "m1", From: "+1555", PhoneNumberID: "pn1"})
+	c.Add(delivery.Event{ID: "m2", From: "+1555", PhoneNumberID: "pn2"})
+	c.FlushSender(Key(delivery.Event{From: "+1555", PhoneNumberID: "pn1"}))
+
+	bursts := r.wait(t, 1)
+	if len(bursts) != 1 || len(bursts[0]) != 1 || bursts[0][0] != "m1" {
+		t.Fatalf("bursts = %v, want only [m1]", bursts)
+	}
+	if c.Pending() != 1 {
+		t.Errorf("Pending = %d, want the other number's message held", c.Pending())
+	}
+}
+
+func TestCoalescer_Disabled(t *testing.T) {
+	r := newRecorder()
+	c := &Coalescer{Flush: r.flush}
+
+	c.Add(delivery.Event{ID: "m1", From: "+1555"})
+	c.Add(delivery.Event{ID: "m2", From: "+1555"})
+
+	bursts := r.wait(t, 2)
+	if len(bursts) != 2 || len(bursts[0]) != 1 {
+		t.Fatalf("bursts = %v, want each event on its own", bursts)
+	}
+}
@@ -0,0 +1,118 @@
+// Package inbound schedules the processing of inbound WhatsApp messages.
+// Messages from one sender are handled one at a time, in arrival order,
+// while a global cap bounds how many senders are served concurrently.
+package inbound
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by Submit when the sender already has QueueLimit
+// messages waiting, or when MaxQueued messages are waiting across all senders.
+var ErrQueueFull = errors.New("inbound: sender queue full")
+
+// Pool runs jobs in per-sender FIFO lanes. A sender with pending work joins a
+// FIFO of ready lanes, and at most Concurrency workers take one job at a time
+// from the front of it, so the number of goroutines stays bounded however
+// many senders are waiting. A lane goes to the back of the FIFO after each
+// job, which keeps busy senders from starving the others. The zero value runs
+// lanes without a global cap or queue limit.
+type Pool struct {
+	Concurrency int // jobs running at once across all senders; <= 0 = unlimited
+	QueueLimit  int // jobs waiting per sender, excluding the running one; <= 0 = unlimited
+	MaxQueued   int // jobs waiting across all senders; <= 0 = unlimited
+
+	mu      sync.Mutex
+	lanes   map[string]*lane
+	ready   []*lane // lanes with pending jobs and none running, in turn order
+	workers int     // worker goroutines alive
+	depth   int     // jobs waiting across all lanes
+	running int     // jobs currently running
+}
+
+// lane is one sender's FIFO of pending jobs.
+type lane struct {
+	sender    string
+	jobs      []func()
+	scheduled bool // the lane is in the ready FIFO or one of its jobs is running
+}
+
+// Submit queues job behind any earlier jobs for sender. It returns
+// ErrQueueFull, without queueing, when the sender's lane is at QueueLimit or
+// the pool is at MaxQueued.
+func (p *Pool) Submit(sender string, job func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lanes == nil {
+		p.lanes = make(map[string]*lane)
+	}
+	l := p.lanes[sender]
+	if p.QueueLimit > 0 && l != nil && len(l.jobs) >= p.QueueLimit {
+		return ErrQueueFull
+	}
+	if p.MaxQueued > 0 && p.depth >= p.MaxQueued {
+		return ErrQueueFull
+	}
+	if l == nil {
+		l = &lane{sender: sender}
+		p.lanes[sender] = l
+	}
+	l.jobs = append(l.jobs, job)
+	p.depth++
+	if !l.scheduled {
+		l.scheduled = true
+		p.ready = append(p.ready, l)
+		if p.Concurrency <= 0 || p.workers < p.Concurrency {
+			p.workers++
+			go p.work()
+		}
+	}
+	return nil
+}
+
+// Depth returns the number of jobs waiting to run.
+func (p *Pool) Depth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.depth
+}
+
+// Running returns the number of jobs currently running.
+func (p *Pool) Running() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// work runs the next job of the lane at the front of the ready FIFO until no
+// lane is ready, then exits. A lane with jobs left goes back to the end of
+// the FIFO; an empty one is removed.
+func (p *Pool) work() {
+	p.mu.Lock()
+	for len(p.ready) > 0 {
+		l := p.ready[0]
+		p.ready[0] = nil
+		p.ready = p.ready[1:]
+		job := l.jobs[0]
+		l.jobs[0] = nil
+		l.jobs = l.jobs[1:]
+		p.depth--
+		p.running++
+		p.mu.Unlock()
+
+		job()
+
+		p.mu.Lock()
+		p.running--
+		if len(l.jobs) > 0 {
+			p.ready = append(p.ready, l)
+		} else {
+			l.scheduled = false
+			delete(p.lanes, l.sender)
+		}
+	}
+	p.workers--
+	p.mu.Unlock()
+}
@@ -0,0 +1,195 @@
+package inbound
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_PerSenderOrder(t *testing.T) {
+	p := &Pool{Concurrency: 4}
+
+	var mu sync.Mutex
+	var got []int
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		i := i
+		wg.Add(1)
+		if err := p.Submit("+1555", func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			got = append(got, i)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("jobs ran out of order: %v", got)
+		}
+	}
+}
+
+func TestPool_SameSenderNeverParallel(t *testing.T) {
+	p := &Pool{}
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		_ = p.Submit("+1555", func() {
+			defer wg.Done()
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		})
+	}
+	wg.Wait()
+
+	if maxInFlight != 1 {
+		t.Errorf("max concurrent jobs for one sender = %d, want 1", maxInFlight)
+	}
+}
+
+func TestPool_GlobalConcurrencyCap(t *testing.T) {
+	p := &Pool{Concurrency: 2}
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		sender := string(rune('a' + i))
+		_ = p.Submit(sender, func() {
+			defer wg.Done()
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		})
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("max concurrent jobs = %d, want <= 2", maxInFlight)
+	}
+	if maxInFlight < 2 {
+		t.Errorf("max concurrent jobs = %d, expected different senders to run in parallel", maxInFlight)
+	}
+}
+
+func TestPool_QueueLimit(t *testing.T) {
+	p := &Pool{QueueLimit: 2}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	_ = p.Submit("+1555", func() {
+		close(started)
+		<-release
+	})
+	<-started // the running job does not count against the limit
+
+	for i := 0; i < 2; i++ {
+		if err := p.Submit("+1555", func() {}); err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+	}
+	if err := p.Submit("+1555", func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if d := p.Depth(); d != 2 {
+		t.Errorf("Depth = %d, want 2", d)
+	}
+	if r := p.Running(); r != 1 {
+		t.Errorf("Running = %d, want 1", r)
+	}
+
+	// Other senders are unaffected.
+	done := make(chan struct{})
+	if err := p.Submit("+1666", func() { close(done) }); err != nil {
+		t.Fatalf("other sender rejected: %v", err)
+	}
+	<-done
+
+	close(release)
+}
+
+func TestPool_BoundedWorkers(t *testing.T) {
+	p := &Pool{Concurrency: 2}
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		sender := string(rune('a' + i))
+		if err := p.Submit(sender, func() {
+			defer wg.Done()
+			<-release
+		}); err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+	}
+
+	p.mu.Lock()
+	workers := p.workers
+	p.mu.Unlock()
+	if workers != 2 {
+		t.Errorf("workers = %d with 100 waiting senders, want 2", workers)
+	}
+
+	close(release)
+	wg.Wait()
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		workers, lanes := p.workers, len(p.lanes)
+		p.mu.Unlock()
+		if workers == 0 && lanes == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("workers = %d, lanes = %d after draining, want 0", workers, lanes)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPool_MaxQueued(t *testing.T) {
+	p := &Pool{Concurrency: 1, MaxQueued: 2}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	_ = p.Submit("a", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	// Two waiting jobs from different senders fill the pool.
+	for _, sender := range []string{"b", "c"} {
+		if err := p.Submit(sender, func() {}); err != nil {
+			t.Fatalf("submit %s: %v", sender, err)
+		}
+	}
+	if err := p.Submit("d", func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	close(release)
+}
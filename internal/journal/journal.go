@@ -0,0 +1,218 @@
+// Package journal is a durable on-disk log of inbound messages the bridge
+// has accepted but not yet answered. Each event is written (and synced)
+// before it is handed to the agent and marked done once the reply has been
+// sent, so messages in flight during a crash or restart are replayed on the
+// next start instead of being lost.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+// compactEvery bounds how many records are appended before the journal file
+// is rewritten with only the pending entries.
+const compactEvery = 1000
+
+// Entry is an accepted event that has not completed yet.
+type Entry struct {
+	Event    delivery.Event `json:"event"`
+	Accepted time.Time      `json:"accepted"`
+	Attempts int            `json:"attempts"` // times processing was started
+}
+
+// record is one line of the journal file.
+type record struct {
+	Op    string `json:"op"` // "add" or "done"
+	ID    string `json:"id"`
+	Entry *Entry `json:"entry,omitempty"`
+}
+
+// Journal is an append-only JSONL file of add/done records. It is safe for
+// concurrent use.
+type Journal struct {
+	path string
+
+	mu      sync.Mutex
+	f       *os.File
+	pending map[string]Entry
+	writes  int // records appended since the last compaction
+}
+
+// Open loads the journal at path, creating it if needed, and compacts it so
+// only incomplete entries remain.
+func Open(path string) (*Journal, error) {
+	j := &Journal{path: path, pending: make(map[string]Entry)}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	if err := j.compactLocked(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Add records evt as accepted and syncs it to disk. Starting an event that
+// is already pending counts as another attempt.
+func (j *Journal) Add(evt delivery.Event) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e, ok := j.pending[evt.ID]
+	if !ok {
+		e = Entry{Event: evt, Accepted: time.Now().UTC()}
+	}
+	e.Attempts++
+	if err := j.appendLocked(record{Op: "add", ID: evt.ID, Entry: &e}); err != nil {
+		return e, err
+	}
+	j.pending[evt.ID] = e
+	return e, nil
+}
+
+// Done marks the event with id as completed.
+func (j *Journal) Done(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	delete(j.pending, id)
+	if err := j.appendLocked(record{Op: "done", ID: id}); err != nil {
+		return err
+	}
+	if j.writes >= compactEvery {
+		return j.compactLocked()
+	}
+	return nil
+}
+
+// Pending returns the incomplete entries, oldest first.
+func (j *Journal) Pending() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	out := make([]Entry, 0, len(j.pending))
+	for _, e := range j.pending {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Accepted.Before(out[b].Accepted) })
+	return out
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+func (j *Journal) load() error {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open journal: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var r record
+		// A torn last line from a crash is skipped.
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			continue
+		}
+		switch r.Op {
+		case "add":
+			if r.Entry != nil {
+				j.pending[r.ID] = *r.Entry
+			}
+		case "done":
+			delete(j.pending, r.ID)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read journal: %w", err)
+	}
+	return nil
+}
+
+func (j *Journal) appendLocked(r record) error {
+	if j.f == nil {
+		f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open journal: %w", err)
+		}
+		j.f = f
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshal journal record: %w", err)
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := j.f.Sync(); err != nil {
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	j.writes++
+	return nil
+}
+
+// compactLocked rewrites the file with one add record per pending entry.
+func (j *Journal) compactLocked() error {
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".journal-*")
+	if err != nil {
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	for id, e := range j.pending {
+		e := e
+		line, err := json.Marshal(record{Op: "add", ID: id, Entry: &e})
+		if err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("compact journal: %w", err)
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compact journal: %w", err)
+	}
+
+	if j.f != nil {
+		_ = j.f.Close()
+		j.f = nil
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("compact journal: %w", err)
+	}
+	j.writes = 0
+	return nil
+}
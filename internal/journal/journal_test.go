@@ -0,0 +1,101 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/delivery"
+)
+
+func TestJournal_ReplaysIncomplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"wamid.1", "wamid.2", "wamid.3"} {
+		if _, err := j.Add(delivery.Event{ID: id, From: "+1555", Text: "hi " + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Done("wamid.2"); err != nil {
+		t.Fatal(err)
+	}
+	_ = j.Close() // simulated crash: wamid.1 and wamid.3 never completed
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = j.Close() }()
+
+	pending := j.Pending()
+	if len(pending) != 2 || pending[0].Event.ID != "wamid.1" || pending[1].Event.ID != "wamid.3" {
+		t.Fatalf("pending = %+v, want wamid.1 and wamid.3 in order", pending)
+	}
+	if pending[0].Event.Text != "hi wamid.1" || pending[0].Event.From != "+1555" {
+		t.Errorf("event not preserved: %+v", pending[0].Event)
+	}
+	if pending[0].Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", pending[0].Attempts)
+	}
+
+	// Replaying counts as a new attempt.
+	e, err := j.Add(pending[0].Event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Attempts != 2 || !e.Accepted.Equal(pending[0].Accepted) {
+		t.Errorf("replayed entry = %+v", e)
+	}
+}
+
+func TestJournal_CompactsOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = j.Add(delivery.Event{ID: "done"})
+	_, _ = j.Add(delivery.Event{ID: "open"})
+	_ = j.Done("done")
+	_ = j.Close()
+
+	// A torn last line from a crash must not break loading.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	_, _ = f.WriteString(`{"op":"add","id":"tor`)
+	_ = f.Close()
+
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = j.Close() }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"open"`) {
+		t.Errorf("journal after compaction = %q, want only the open entry", data)
+	}
+}
+
+func TestJournal_DoneUnknownIsNoop(t *testing.T) {
+	j, err := Open(filepath.Join(t.TempDir(), "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = j.Close() }()
+	if err := j.Done("never-added"); err != nil {
+		t.Errorf("Done on unknown ID: %v", err)
+	}
+	if len(j.Pending()) != 0 {
+		t.Error("expected no pending entries")
+	}
+}
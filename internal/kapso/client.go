@@ -1,23 +1,36 @@
 package kapso
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const baseURL = "https://api.kapso.ai/meta/whatsapp/v24.0"
 
+// DefaultTimeout bounds a single HTTP request made by a client from NewClient.
+const DefaultTimeout = 60 * time.Second
+
 // Client sends messages via the Kapso WhatsApp API.
+//
+// Every method has a ...Context variant that accepts a context.Context; the
+// plain methods use context.Background(). Failed API calls return an
+// *APIError. Clients created with NewClient retry retryable errors (429,
+// 5xx, Meta throttling codes) with exponential backoff, honouring
+// Retry-After; a zero-value Client does not retry.
 type Client struct {
-	APIKey        string
-	PhoneNumberID string
-	HTTPClient    *http.Client
-	BaseURL       string // if empty, uses the default Kapso API URL
+	APIKey            string
+	PhoneNumberID     string
+	BusinessAccountID string // WhatsApp Business Account ID; needed for ListTemplates
+	HTTPClient        *http.Client
+	BaseURL           string // if empty, uses the default Kapso API URL
+
+	retry retryPolicy
 }
 
 // getBaseURL returns the configured base URL or the default.
@@ -28,17 +41,34 @@ func (c *Client) getBaseURL() string {
 	return baseURL
 }
 
-// NewClient creates a Kapso API client.
+// NewClient creates a Kapso API client with a request timeout and retries.
 func NewClient(apiKey, phoneNumberID string) *Client {
 	return &Client{
 		APIKey:        apiKey,
 		PhoneNumberID: phoneNumberID,
-		HTTPClient:    http.DefaultClient,
+		HTTPClient:    &http.Client{Timeout: DefaultTimeout},
+		retry:         defaultRetryPolicy(),
 	}
 }
 
 // SendText sends a text message to the given phone number.
 func (c *Client) SendText(to, text string) (*SendMessageResponse, error) {
+	return c.SendTextContext(context.Background(), to, text)
+}
+
+// SendTextContext is like SendText but carries ctx.
+func (c *Client) SendTextContext(ctx context.Context, to, text string) (*SendMessageResponse, error) {
+	return c.SendTextReplyContext(ctx, to, text, "")
+}
+
+// SendTextReply sends a text message that quotes replyTo, so it shows up
+// threaded under that message in the chat. An empty replyTo sends a plain text.
+func (c *Client) SendTextReply(to, text, replyTo string) (*SendMessageResponse, error) {
+	return c.SendTextReplyContext(context.Background(), to, text, replyTo)
+}
+
+// SendTextReplyContext is like SendTextReply but carries ctx.
+func (c *Client) SendTextReplyContext(ctx context.Context, to, text, replyTo string) (*SendMessageResponse, error) {
 	req := SendMessageRequest{
 		MessagingProduct: "whatsapp",
 		RecipientType:    "individual",
@@ -46,34 +76,41 @@ func (c *Client) SendText(to, text string) (*SendMessageResponse, error) {
 		Type:             "text",
 		Text:             TextContent{Body: text},
 	}
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+	if replyTo != "" {
+		req.Context = &ReplyContext{MessageID: replyTo}
 	}
+	return c.sendMessage(ctx, req)
+}
 
-	url := fmt.Sprintf("%s/%s/messages", c.getBaseURL(), c.PhoneNumberID)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
+// SendReaction reacts to messageID with emoji. An empty emoji removes a
+// previously sent reaction.
+func (c *Client) SendReaction(to, messageID, emoji string) (*SendMessageResponse, error) {
+	return c.SendReactionContext(context.Background(), to, messageID, emoji)
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-API-Key", c.APIKey)
+// SendReactionContext is like SendReaction but carries ctx.
+func (c *Client) SendReactionContext(ctx context.Context, to, messageID, emoji string) (*SendMessageResponse, error) {
+	return c.sendMessage(ctx, ReactionMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "reaction",
+		Reaction:         ReactionContent{MessageID: messageID, Emoji: emoji},
+	})
+}
 
-	resp, err := c.HTTPClient.Do(httpReq)
+// sendMessage posts any message payload to the messages endpoint and decodes
+// the send response. All Send* methods funnel through here.
+func (c *Client) sendMessage(ctx context.Context, payload interface{}) (*SendMessageResponse, error) {
+	body, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("send request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	respBody, err := io.ReadAll(resp.Body)
+	url := fmt.Sprintf("%s/%s/messages", c.getBaseURL(), c.PhoneNumberID)
+	respBody, err := c.do(ctx, "POST", url, "application/json", body)
 	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("kapso API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, err
 	}
 
 	var result SendMessageResponse
@@ -86,16 +123,26 @@ func (c *Client) SendText(to, text string) (*SendMessageResponse, error) {
 
 // MarkRead marks a message as read. This sends blue checkmarks to the sender.
 func (c *Client) MarkRead(messageID string) error {
-	return c.markRead(messageID, nil)
+	return c.MarkReadContext(context.Background(), messageID)
+}
+
+// MarkReadContext is like MarkRead but carries ctx.
+func (c *Client) MarkReadContext(ctx context.Context, messageID string) error {
+	return c.markRead(ctx, messageID, nil)
 }
 
 // MarkReadWithTyping marks a message as read and shows a typing indicator.
 func (c *Client) MarkReadWithTyping(messageID string) error {
-	return c.markRead(messageID, &TypingIndicator{Type: "text"})
+	return c.MarkReadWithTypingContext(context.Background(), messageID)
+}
+
+// MarkReadWithTypingContext is like MarkReadWithTyping but carries ctx.
+func (c *Client) MarkReadWithTypingContext(ctx context.Context, messageID string) error {
+	return c.markRead(ctx, messageID, &TypingIndicator{Type: "text"})
 }
 
 // markRead posts a mark-as-read request, optionally with a typing indicator.
-func (c *Client) markRead(messageID string, typing *TypingIndicator) error {
+func (c *Client) markRead(ctx context.Context, messageID string, typing *TypingIndicator) error {
 	req := MarkReadRequest{
 		MessagingProduct: "whatsapp",
 		Status:           "read",
@@ -109,24 +156,8 @@ func (c *Client) markRead(messageID string, typing *TypingIndicator) error {
 	}
 
 	url := fmt.Sprintf("%s/%s/messages", c.getBaseURL(), c.PhoneNumberID)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-API-Key", c.APIKey)
-
-	resp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("send request: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	_, _ = io.Copy(io.Discard, resp.Body)
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("mark read error (status %d)", resp.StatusCode)
+	if _, err := c.do(ctx, "POST", url, "application/json", body); err != nil {
+		return fmt.Errorf("mark read: %w", err)
 	}
 
 	return nil
@@ -137,6 +168,11 @@ func (c *Client) markRead(messageID string, typing *TypingIndicator) error {
 // a +1 sentinel: if the server sends more than maxBytes, an error is returned.
 // Only HTTPS URLs with allowed hostnames are accepted to prevent SSRF.
 func (c *Client) DownloadMedia(rawURL string, maxBytes int64) ([]byte, error) {
+	return c.DownloadMediaContext(context.Background(), rawURL, maxBytes)
+}
+
+// DownloadMediaContext is like DownloadMedia but carries ctx.
+func (c *Client) DownloadMediaContext(ctx context.Context, rawURL string, maxBytes int64) ([]byte, error) {
 	safeURL, err := sanitizeMediaURL(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid media URL: %w", err)
@@ -144,7 +180,7 @@ func (c *Client) DownloadMedia(rawURL string, maxBytes int64) ([]byte, error) {
 
 	// Use the reconstructed URL (safeURL.String()) instead of the raw input
 	// to break the taint chain for static analysis (CodeQL go/request-forgery).
-	req, err := http.NewRequest("GET", safeURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", safeURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -143,6 +143,53 @@ func TestMarkReadWithTyping(t *testing.T) {
 	})
 }
 
+func TestSendReaction(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	if _, err := client.SendReaction("+15551234567", "wamid.in1", "✅"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if payload["type"] != "reaction" {
+		t.Errorf("type = %v, want reaction", payload["type"])
+	}
+	r, ok := payload["reaction"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("reaction object missing: %v", payload)
+	}
+	if r["message_id"] != "wamid.in1" || r["emoji"] != "✅" {
+		t.Errorf("reaction = %v", r)
+	}
+}
+
+func TestSendTextReply(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	if _, err := client.SendTextReply("+15551234567", "sure", "wamid.in1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, ok := payload["context"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("context object missing: %v", payload)
+	}
+	if ctx["message_id"] != "wamid.in1" {
+		t.Errorf("context.message_id = %v, want wamid.in1", ctx["message_id"])
+	}
+
+	// Without a message to quote, no context is sent.
+	payload = nil
+	if _, err := client.SendTextReply("+15551234567", "sure", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := payload["context"]; ok {
+		t.Errorf("unexpected context for plain reply: %v", payload)
+	}
+}
+
 func TestSanitizeMediaURL(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,138 @@
+package kapso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ContactCard is a shared contact (vCard). The same shape is used for
+// inbound "contacts" messages and for SendContacts.
+type ContactCard struct {
+	Name      ContactName      `json:"name"`
+	Phones    []ContactPhone   `json:"phones,omitempty"`
+	Emails    []ContactEmail   `json:"emails,omitempty"`
+	Org       *ContactOrg      `json:"org,omitempty"`
+	URLs      []ContactURL     `json:"urls,omitempty"`
+	Addresses []ContactAddress `json:"addresses,omitempty"`
+	Birthday  string           `json:"birthday,omitempty"` // YYYY-MM-DD
+}
+
+// ContactName is the name of a shared contact. FormattedName is required
+// when sending.
+type ContactName struct {
+	FormattedName string `json:"formatted_name"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+}
+
+// ContactPhone is a phone number of a shared contact. WaID is set on inbound
+// cards when the number has WhatsApp.
+type ContactPhone struct {
+	Phone string `json:"phone,omitempty"`
+	WaID  string `json:"wa_id,omitempty"`
+	Type  string `json:"type,omitempty"` // CELL, MAIN, HOME, WORK, ...
+}
+
+// ContactEmail is an email address of a shared contact.
+type ContactEmail struct {
+	Email string `json:"email"`
+	Type  string `json:"type,omitempty"`
+}
+
+// ContactOrg is the organization of a shared contact.
+type ContactOrg struct {
+	Company    string `json:"company,omitempty"`
+	Department string `json:"department,omitempty"`
+	Title      string `json:"title,omitempty"`
+}
+
+// ContactURL is a website of a shared contact.
+type ContactURL struct {
+	URL  string `json:"url"`
+	Type string `json:"type,omitempty"`
+}
+
+// ContactAddress is a postal address of a shared contact.
+type ContactAddress struct {
+	Street      string `json:"street,omitempty"`
+	City        string `json:"city,omitempty"`
+	State       string `json:"state,omitempty"`
+	Zip         string `json:"zip,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// DisplayName returns the formatted name, falling back to first and last name.
+func (c ContactCard) DisplayName() string {
+	if c.Name.FormattedName != "" {
+		return c.Name.FormattedName
+	}
+	return strings.TrimSpace(c.Name.FirstName + " " + c.Name.LastName)
+}
+
+// LocationMessageRequest is the payload for sending a location pin.
+type LocationMessageRequest struct {
+	MessagingProduct string          `json:"messaging_product"`
+	RecipientType    string          `json:"recipient_type"`
+	To               string          `json:"to"`
+	Type             string          `json:"type"`
+	Location         LocationContent `json:"location"`
+}
+
+// ContactsMessageRequest is the payload for sending one or more contact cards.
+type ContactsMessageRequest struct {
+	MessagingProduct string        `json:"messaging_product"`
+	RecipientType    string        `json:"recipient_type"`
+	To               string        `json:"to"`
+	Type             string        `json:"type"`
+	Contacts         []ContactCard `json:"contacts"`
+}
+
+// SendLocation sends a location pin. Name and Address are optional.
+func (c *Client) SendLocation(to string, loc LocationContent) (*SendMessageResponse, error) {
+	return c.SendLocationContext(context.Background(), to, loc)
+}
+
+// SendLocationContext is like SendLocation but carries ctx.
+func (c *Client) SendLocationContext(ctx context.Context, to string, loc LocationContent) (*SendMessageResponse, error) {
+	if loc.Latitude < -90 || loc.Latitude > 90 || loc.Longitude < -180 || loc.Longitude > 180 {
+		return nil, fmt.Errorf("location: coordinates (%f, %f) out of range", loc.Latitude, loc.Longitude)
+	}
+	return c.sendMessage(ctx, LocationMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "location",
+		Location:         loc,
+	})
+}
+
+// SendContacts sends one or more contact cards. Each card needs a name;
+// FormattedName is derived from the first and last name when empty.
+func (c *Client) SendContacts(to string, cards []ContactCard) (*SendMessageResponse, error) {
+	return c.SendContactsContext(context.Background(), to, cards)
+}
+
+// SendContactsContext is like SendContacts but carries ctx.
+func (c *Client) SendContactsContext(ctx context.Context, to string, cards []ContactCard) (*SendMessageResponse, error) {
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("contacts: at least one contact is required")
+	}
+	out := make([]ContactCard, len(cards))
+	for i, card := range cards {
+		card.Name.FormattedName = card.DisplayName()
+		if card.Name.FormattedName == "" {
+			return nil, fmt.Errorf("contacts: contact %d has no name", i+1)
+		}
+		out[i] = card
+	}
+	return c.sendMessage(ctx, ContactsMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "contacts",
+		Contacts:         out,
+	})
+}
@@ -0,0 +1,96 @@
+package kapso
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSendLocation(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	_, err := client.SendLocation("+15551234567", LocationContent{
+		Latitude:  -12.046374,
+		Longitude: -77.042793,
+		Name:      "Lima office",
+		Address:   "Av. Larco 123",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if payload["type"] != "location" {
+		t.Errorf("type = %v, want location", payload["type"])
+	}
+	loc, ok := payload["location"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("location object missing: %v", payload)
+	}
+	if loc["latitude"] != -12.046374 || loc["name"] != "Lima office" {
+		t.Errorf("location = %v", loc)
+	}
+
+	if _, err := client.SendLocation("+1", LocationContent{Latitude: 91}); err == nil {
+		t.Error("expected error for latitude out of range")
+	}
+}
+
+func TestSendContacts(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	_, err := client.SendContacts("+15551234567", []ContactCard{{
+		Name:   ContactName{FirstName: "Ana", LastName: "Pérez"},
+		Phones: []ContactPhone{{Phone: "+51987654321", Type: "CELL"}},
+		Emails: []ContactEmail{{Email: "ana@example.com"}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if payload["type"] != "contacts" {
+		t.Errorf("type = %v, want contacts", payload["type"])
+	}
+	cards, ok := payload["contacts"].([]interface{})
+	if !ok || len(cards) != 1 {
+		t.Fatalf("contacts = %v", payload["contacts"])
+	}
+	name := cards[0].(map[string]interface{})["name"].(map[string]interface{})
+	if name["formatted_name"] != "Ana Pérez" {
+		t.Errorf("formatted_name = %v, want derived \"Ana Pérez\"", name["formatted_name"])
+	}
+
+	if _, err := client.SendContacts("+1", nil); err == nil {
+		t.Error("expected error for no contacts")
+	}
+	if _, err := client.SendContacts("+1", []ContactCard{{Phones: []ContactPhone{{Phone: "+1"}}}}); err == nil {
+		t.Error("expected error for contact without a name")
+	}
+}
+
+func TestInboundContactsJSON(t *testing.T) {
+	raw := `{
+		"id": "wamid.vcard",
+		"from": "15551234567",
+		"type": "contacts",
+		"contacts": [{
+			"name": {"formatted_name": "Ana Pérez", "first_name": "Ana"},
+			"phones": [{"phone": "+51 987 654 321", "wa_id": "51987654321", "type": "CELL"}],
+			"emails": [{"email": "ana@example.com", "type": "WORK"}],
+			"org": {"company": "Acme"}
+		}]
+	}`
+	var msg Message
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(msg.Contacts) != 1 {
+		t.Fatalf("contacts = %+v", msg.Contacts)
+	}
+	c := msg.Contacts[0]
+	if c.DisplayName() != "Ana Pérez" || c.Phones[0].WaID != "51987654321" || c.Emails[0].Email != "ana@example.com" || c.Org.Company != "Acme" {
+		t.Errorf("contact = %+v", c)
+	}
+}
@@ -0,0 +1,93 @@
+package kapso
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned when the Kapso API answers with a non-success status.
+// Code and Subcode carry the Meta Graph API error code when the body has one.
+type APIError struct {
+	StatusCode int
+	Code       int           // Meta error code (e.g. 131056 pair rate limit)
+	Subcode    int           // Meta error_subcode, if any
+	Message    string        // Meta error message, if any
+	Body       string        // raw response body
+	RetryAfter time.Duration // from the Retry-After header; 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kapso API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again later:
+// 429s, 5xx responses and Meta's throttling error codes.
+func (e *APIError) Retryable() bool {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 {
+		return true
+	}
+	switch e.Code {
+	case 4, // application request limit reached
+		80007,  // WhatsApp Business Account rate limit
+		130429, // Cloud API throughput reached
+		131056: // pair rate limit (too many messages to one user)
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether err is, or wraps, a retryable *APIError.
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable()
+}
+
+// newAPIError builds an APIError from a failed response and its body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var graph struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+			Subcode int    `json:"error_subcode"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &graph) == nil {
+		e.Code = graph.Error.Code
+		e.Subcode = graph.Error.Subcode
+		e.Message = graph.Error.Message
+	}
+
+	return e
+}
+
+// parseRetryAfter parses a Retry-After header given either as delay seconds
+// or as an HTTP date. Unparseable or past values yield 0.
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package kapso
+
+import (
+	"context"
+	"fmt"
+)
+
+// WhatsApp limits for interactive messages.
+const (
+	MaxReplyButtons   = 3
+	MaxListRows       = 10
+	MaxButtonTitleLen = 20
+	MaxRowTitleLen    = 24
+)
+
+// InteractiveMessageRequest is the payload for sending reply buttons or a list message.
+type InteractiveMessageRequest struct {
+	MessagingProduct string      `json:"messaging_product"`
+	RecipientType    string      `json:"recipient_type"`
+	To               string      `json:"to"`
+	Type             string      `json:"type"`
+	Interactive      Interactive `json:"interactive"`
+}
+
+// Interactive is the interactive object of an outbound message.
+// Type is "button" for reply buttons or "list" for a list message.
+type Interactive struct {
+	Type   string            `json:"type"`
+	Header *InteractiveText  `json:"header,omitempty"`
+	Body   InteractiveBody   `json:"body"`
+	Footer *InteractiveBody  `json:"footer,omitempty"`
+	Action InteractiveAction `json:"action"`
+}
+
+// InteractiveText is a text header for an interactive message.
+type InteractiveText struct {
+	Type string `json:"type"` // "text"
+	Text string `json:"text"`
+}
+
+// InteractiveBody holds the body or footer text of an interactive message.
+type InteractiveBody struct {
+	Text string `json:"text"`
+}
+
+// InteractiveAction carries the buttons (reply buttons) or the menu button
+// label and sections (list messages).
+type InteractiveAction struct {
+	Buttons  []ReplyButton `json:"buttons,omitempty"`
+	Button   string        `json:"button,omitempty"`
+	Sections []ListSection `json:"sections,omitempty"`
+}
+
+// ReplyButton wraps a single quick-reply button.
+type ReplyButton struct {
+	Type  string      `json:"type"` // "reply"
+	Reply ButtonReply `json:"reply"`
+}
+
+// ButtonReply identifies a reply button. The same shape is echoed back in
+// the inbound interactive.button_reply when the user taps it.
+type ButtonReply struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ListSection groups rows in a list message.
+type ListSection struct {
+	Title string    `json:"title,omitempty"`
+	Rows  []ListRow `json:"rows"`
+}
+
+// ListRow is a selectable row in a list message. The same shape is echoed
+// back in the inbound interactive.list_reply when the user picks it.
+type ListRow struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// SendButtons sends a message with up to three quick-reply buttons.
+func (c *Client) SendButtons(to, body string, buttons []ButtonReply) (*SendMessageResponse, error) {
+	return c.SendButtonsContext(context.Background(), to, body, buttons)
+}
+
+// SendButtonsContext is like SendButtons but carries ctx.
+func (c *Client) SendButtonsContext(ctx context.Context, to, body string, buttons []ButtonReply) (*SendMessageResponse, error) {
+	if len(buttons) == 0 || len(buttons) > MaxReplyButtons {
+		return nil, fmt.Errorf("reply buttons: need 1-%d buttons, got %d", MaxReplyButtons, len(buttons))
+	}
+
+	action := InteractiveAction{}
+	for _, b := range buttons {
+		if len([]rune(b.Title)) > MaxButtonTitleLen {
+			return nil, fmt.Errorf("reply buttons: title %q exceeds %d characters", b.Title, MaxButtonTitleLen)
+		}
+		action.Buttons = append(action.Buttons, ReplyButton{Type: "reply", Reply: b})
+	}
+
+	return c.SendInteractiveContext(ctx, to, Interactive{
+		Type:   "button",
+		Body:   InteractiveBody{Text: body},
+		Action: action,
+	})
+}
+
+// SendList sends a list message. buttonText is the label of the button that
+// opens the list; sections may hold at most ten rows in total.
+func (c *Client) SendList(to, body, buttonText string, sections []ListSection) (*SendMessageResponse, error) {
+	return c.SendListContext(context.Background(), to, body, buttonText, sections)
+}
+
+// SendListContext is like SendList but carries ctx.
+func (c *Client) SendListContext(ctx context.Context, to, body, buttonText string, sections []ListSection) (*SendMessageResponse, error) {
+	rows := 0
+	for _, s := range sections {
+		for _, r := range s.Rows {
+			if len([]rune(r.Title)) > MaxRowTitleLen {
+				return nil, fmt.Errorf("list: row title %q exceeds %d characters", r.Title, MaxRowTitleLen)
+			}
+		}
+		rows += len(s.Rows)
+	}
+	if rows == 0 || rows > MaxListRows {
+		return nil, fmt.Errorf("list: need 1-%d rows, got %d", MaxListRows, rows)
+	}
+	if buttonText == "" {
+		return nil, fmt.Errorf("list: button text is required")
+	}
+
+	return c.SendInteractiveContext(ctx, to, Interactive{
+		Type: "list",
+		Body: InteractiveBody{Text: body},
+		Action: InteractiveAction{
+			Button:   buttonText,
+			Sections: sections,
+		},
+	})
+}
+
+// SendInteractive sends an arbitrary interactive object. Prefer SendButtons
+// or SendList, which validate WhatsApp's limits before calling the API.
+func (c *Client) SendInteractive(to string, interactive Interactive) (*SendMessageResponse, error) {
+	return c.SendInteractiveContext(context.Background(), to, interactive)
+}
+
+// SendInteractiveContext is like SendInteractive but carries ctx.
+func (c *Client) SendInteractiveContext(ctx context.Context, to string, interactive Interactive) (*SendMessageResponse, error) {
+	return c.sendMessage(ctx, InteractiveMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "interactive",
+		Interactive:      interactive,
+	})
+}
@@ -0,0 +1,150 @@
+package kapso
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureClient returns a client whose requests are decoded into *payload.
+func captureClient(t *testing.T, payload *map[string]interface{}) (*Client, func()) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(payload)
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.out"}]}`))
+	}))
+	client := &Client{
+		APIKey:        "test-key",
+		PhoneNumberID: "12345",
+		HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+	}
+	return client, srv.Close
+}
+
+func TestSendButtons(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	_, err := client.SendButtons("+15551234567", "Pick one", []ButtonReply{
+		{ID: "yes", Title: "Yes"},
+		{ID: "no", Title: "No"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if payload["type"] != "interactive" {
+		t.Errorf("type = %v, want interactive", payload["type"])
+	}
+	in := payload["interactive"].(map[string]interface{})
+	if in["type"] != "button" {
+		t.Errorf("interactive.type = %v, want button", in["type"])
+	}
+	if in["body"].(map[string]interface{})["text"] != "Pick one" {
+		t.Errorf("body.text = %v", in["body"])
+	}
+	buttons := in["action"].(map[string]interface{})["buttons"].([]interface{})
+	if len(buttons) != 2 {
+		t.Fatalf("got %d buttons, want 2", len(buttons))
+	}
+	first := buttons[0].(map[string]interface{})
+	if first["type"] != "reply" {
+		t.Errorf("button type = %v, want reply", first["type"])
+	}
+	if reply := first["reply"].(map[string]interface{}); reply["id"] != "yes" || reply["title"] != "Yes" {
+		t.Errorf("first reply = %v", reply)
+	}
+}
+
+func TestSendButtonsValidation(t *testing.T) {
+	client := &Client{APIKey: "k", PhoneNumberID: "1", HTTPClient: http.DefaultClient}
+
+	tests := []struct {
+		name    string
+		buttons []ButtonReply
+		wantErr string
+	}{
+		{"none", nil, "1-3 buttons"},
+		{"too many", []ButtonReply{{ID: "a", Title: "a"}, {ID: "b", Title: "b"}, {ID: "c", Title: "c"}, {ID: "d", Title: "d"}}, "1-3 buttons"},
+		{"title too long", []ButtonReply{{ID: "a", Title: strings.Repeat("x", 21)}}, "exceeds 20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.SendButtons("+1", "body", tt.buttons)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSendList(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	_, err := client.SendList("+15551234567", "Choose a slot", "Slots", []ListSection{
+		{Title: "Morning", Rows: []ListRow{{ID: "m9", Title: "9:00", Description: "Front desk"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	in := payload["interactive"].(map[string]interface{})
+	if in["type"] != "list" {
+		t.Errorf("interactive.type = %v, want list", in["type"])
+	}
+	action := in["action"].(map[string]interface{})
+	if action["button"] != "Slots" {
+		t.Errorf("action.button = %v, want Slots", action["button"])
+	}
+	sections := action["sections"].([]interface{})
+	rows := sections[0].(map[string]interface{})["rows"].([]interface{})
+	if row := rows[0].(map[string]interface{}); row["id"] != "m9" || row["description"] != "Front desk" {
+		t.Errorf("row = %v", row)
+	}
+}
+
+func TestSendListValidation(t *testing.T) {
+	client := &Client{APIKey: "k", PhoneNumberID: "1", HTTPClient: http.DefaultClient}
+
+	if _, err := client.SendList("+1", "body", "Open", nil); err == nil {
+		t.Error("expected error for empty list")
+	}
+	if _, err := client.SendList("+1", "body", "", []ListSection{{Rows: []ListRow{{ID: "a", Title: "a"}}}}); err == nil {
+		t.Error("expected error for missing button text")
+	}
+
+	var rows []ListRow
+	for i := 0; i < 11; i++ {
+		rows = append(rows, ListRow{ID: "r", Title: "r"})
+	}
+	if _, err := client.SendList("+1", "body", "Open", []ListSection{{Rows: rows}}); err == nil {
+		t.Error("expected error for more than 10 rows")
+	}
+}
+
+func TestInboundMessageJSON_Interactive(t *testing.T) {
+	raw := `{
+		"id": "wamid.int",
+		"type": "interactive",
+		"from": "1234567890",
+		"interactive": {
+			"type": "list_reply",
+			"list_reply": {"id": "m9", "title": "9:00", "description": "Front desk"}
+		}
+	}`
+	var msg InboundMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if msg.Interactive == nil || msg.Interactive.ListReply == nil {
+		t.Fatal("list_reply not parsed")
+	}
+	if msg.Interactive.ListReply.ID != "m9" {
+		t.Errorf("list_reply.id = %q, want m9", msg.Interactive.ListReply.ID)
+	}
+}
@@ -1,10 +1,9 @@
 package kapso
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 )
 
@@ -39,6 +38,11 @@ type Paging struct {
 
 // ListMessages fetches messages from the Kapso API.
 func (c *Client) ListMessages(params ListMessagesParams) (*ListMessagesResponse, error) {
+	return c.ListMessagesContext(context.Background(), params)
+}
+
+// ListMessagesContext is like ListMessages but carries ctx.
+func (c *Client) ListMessagesContext(ctx context.Context, params ListMessagesParams) (*ListMessagesResponse, error) {
 	u, err := url.Parse(fmt.Sprintf("%s/%s/messages", c.getBaseURL(), c.PhoneNumberID))
 	if err != nil {
 		return nil, fmt.Errorf("parse URL: %w", err)
@@ -59,27 +63,10 @@ func (c *Client) ListMessages(params ListMessagesParams) (*ListMessagesResponse,
 	}
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("X-API-Key", c.APIKey)
-
-	resp, err := c.HTTPClient.Do(req)
+	body, err := c.do(ctx, "GET", u.String(), "", nil)
 	if err != nil {
 		return nil, fmt.Errorf("list messages: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("kapso API error (status %d): %s", resp.StatusCode, string(body))
-	}
 
 	var result ListMessagesResponse
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -88,3 +75,76 @@ func (c *Client) ListMessages(params ListMessagesParams) (*ListMessagesResponse,
 
 	return &result, nil
 }
+
+// MessageIterator walks every page of a message listing, following
+// Paging.Cursors.After. Use it as:
+//
+//	it := client.ListAllMessages(ctx, params)
+//	for it.Next() {
+//		msg := it.Message()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type MessageIterator struct {
+	client *Client
+	ctx    context.Context
+	params ListMessagesParams
+	page   []InboundMessage
+	pos    int
+	cur    InboundMessage
+	last   bool // no further pages
+	pages  int
+	err    error
+}
+
+// ListAllMessages returns an iterator over all messages matching params,
+// across pages. params.Limit is the page size.
+func (c *Client) ListAllMessages(ctx context.Context, params ListMessagesParams) *MessageIterator {
+	return &MessageIterator{client: c, ctx: ctx, params: params}
+}
+
+// Next advances to the next message, fetching the next page when needed. It
+// returns false when all pages are exhausted or a request failed.
+func (it *MessageIterator) Next() bool {
+	for it.pos >= len(it.page) {
+		if it.last || it.err != nil {
+			return false
+		}
+
+		resp, err := it.client.ListMessagesContext(it.ctx, it.params)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.pages++
+		it.page, it.pos = resp.Data, 0
+
+		next := ""
+		if resp.Paging != nil {
+			next = resp.Paging.Cursors.After
+		}
+		// Stop on an empty page or a cursor that does not move.
+		if next == "" || next == it.params.After || len(resp.Data) == 0 {
+			it.last = true
+		}
+		it.params.After = next
+	}
+
+	it.cur = it.page[it.pos]
+	it.pos++
+	return true
+}
+
+// Message returns the current message. Call it after Next returns true.
+func (it *MessageIterator) Message() InboundMessage {
+	return it.cur
+}
+
+// Pages returns the number of pages fetched so far.
+func (it *MessageIterator) Pages() int {
+	return it.pages
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *MessageIterator) Err() error {
+	return it.err
+}
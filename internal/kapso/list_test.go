@@ -1,7 +1,11 @@
 package kapso
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -52,3 +56,79 @@ func TestInboundMessageJSON_Document(t *testing.T) {
 		t.Fatal("document not parsed correctly")
 	}
 }
+
+// pagedServer serves three pages of one message each, linked by cursors.
+// failAt, when > 0, makes that page return a 400.
+func pagedServer(t *testing.T, failAt int) (*Client, *[]string, func()) {
+	t.Helper()
+	var cursors []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		after := r.URL.Query().Get("after")
+		cursors = append(cursors, after)
+		page := map[string]int{"": 1, "c2": 2, "c3": 3}[after]
+		if page == failAt {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad cursor"}}`))
+			return
+		}
+		next := ""
+		if page < 3 {
+			next = fmt.Sprintf("c%d", page+1)
+		}
+		_, _ = fmt.Fprintf(w, `{"data":[{"id":"wamid.%d","from":"1","type":"text","text":{"body":"m%d"}}],
+			"paging":{"cursors":{"after":%q}}}`, page, page, next)
+	}))
+	client := &Client{
+		APIKey:        "test-key",
+		PhoneNumberID: "12345",
+		HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+	}
+	return client, &cursors, srv.Close
+}
+
+func TestListAllMessages(t *testing.T) {
+	t.Run("follows cursors across pages", func(t *testing.T) {
+		client, cursors, done := pagedServer(t, 0)
+		defer done()
+
+		it := client.ListAllMessages(context.Background(), ListMessagesParams{Direction: "inbound", Limit: 1})
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Message().ID)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"wamid.1", "wamid.2", "wamid.3"}
+		if fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Errorf("ids = %v, want %v", ids, want)
+		}
+		if fmt.Sprint(*cursors) != fmt.Sprint([]string{"", "c2", "c3"}) {
+			t.Errorf("cursors requested = %q", *cursors)
+		}
+		if it.Pages() != 3 {
+			t.Errorf("Pages() = %d, want 3", it.Pages())
+		}
+	})
+
+	t.Run("stops and reports a failing page", func(t *testing.T) {
+		client, _, done := pagedServer(t, 2)
+		defer done()
+
+		it := client.ListAllMessages(context.Background(), ListMessagesParams{Limit: 1})
+		n := 0
+		for it.Next() {
+			n++
+		}
+		if n != 1 {
+			t.Errorf("got %d messages before the error, want 1", n)
+		}
+		if it.Err() == nil {
+			t.Fatal("expected error from page 2")
+		}
+		if it.Next() {
+			t.Error("Next() after an error should return false")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package kapso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// MediaObject references the media of an outbound message. Exactly one of ID
+// (returned by UploadMedia) or Link (a public HTTPS URL) should be set.
+type MediaObject struct {
+	ID       string `json:"id,omitempty"`
+	Link     string `json:"link,omitempty"`
+	Caption  string `json:"caption,omitempty"`  // image, document, video only
+	Filename string `json:"filename,omitempty"` // document only
+}
+
+// MediaMessageRequest is the payload for sending an image, document, audio or
+// video message. Only the field matching Type is populated.
+type MediaMessageRequest struct {
+	MessagingProduct string       `json:"messaging_product"`
+	RecipientType    string       `json:"recipient_type"`
+	To               string       `json:"to"`
+	Type             string       `json:"type"`
+	Image            *MediaObject `json:"image,omitempty"`
+	Document         *MediaObject `json:"document,omitempty"`
+	Audio            *MediaObject `json:"audio,omitempty"`
+	Video            *MediaObject `json:"video,omitempty"`
+}
+
+// UploadMediaResponse is the response from the media upload API.
+type UploadMediaResponse struct {
+	ID string `json:"id"`
+}
+
+// MediaKind maps a MIME type to the WhatsApp message type that can carry it:
+// "image", "audio", "video", or "document" for everything else. Formats that
+// WhatsApp does not render inline (e.g. image/gif) are sent as documents.
+func MediaKind(mimeType string) string {
+	mt := strings.ToLower(strings.TrimSpace(mimeType))
+	if i := strings.Index(mt, ";"); i >= 0 {
+		mt = strings.TrimSpace(mt[:i])
+	}
+	switch mt {
+	case "image/jpeg", "image/png":
+		return "image"
+	case "audio/aac", "audio/amr", "audio/mpeg", "audio/mp4", "audio/ogg":
+		return "audio"
+	case "video/mp4", "video/3gpp":
+		return "video"
+	default:
+		return "document"
+	}
+}
+
+// SendImage sends an image message. Caption is optional.
+func (c *Client) SendImage(to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(context.Background(), to, "image", media)
+}
+
+// SendImageContext is like SendImage but carries ctx.
+func (c *Client) SendImageContext(ctx context.Context, to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(ctx, to, "image", media)
+}
+
+// SendDocument sends a document message. Filename controls the name shown
+// to the recipient; Caption is optional.
+func (c *Client) SendDocument(to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(context.Background(), to, "document", media)
+}
+
+// SendDocumentContext is like SendDocument but carries ctx.
+func (c *Client) SendDocumentContext(ctx context.Context, to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(ctx, to, "document", media)
+}
+
+// SendAudio sends an audio message (voice notes use audio/ogg with opus).
+// Audio messages do not support captions or filenames.
+func (c *Client) SendAudio(to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(context.Background(), to, "audio", media)
+}
+
+// SendAudioContext is like SendAudio but carries ctx.
+func (c *Client) SendAudioContext(ctx context.Context, to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(ctx, to, "audio", media)
+}
+
+// SendVideo sends a video message. Caption is optional.
+func (c *Client) SendVideo(to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(context.Background(), to, "video", media)
+}
+
+// SendVideoContext is like SendVideo but carries ctx.
+func (c *Client) SendVideoContext(ctx context.Context, to string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(ctx, to, "video", media)
+}
+
+// SendMedia sends a media message of the given kind ("image", "document",
+// "audio" or "video"). Fields the kind does not support are dropped.
+func (c *Client) SendMedia(to, kind string, media MediaObject) (*SendMessageResponse, error) {
+	return c.SendMediaContext(context.Background(), to, kind, media)
+}
+
+// SendMediaContext is like SendMedia but carries ctx.
+func (c *Client) SendMediaContext(ctx context.Context, to, kind string, media MediaObject) (*SendMessageResponse, error) {
+	if media.ID == "" && media.Link == "" {
+		return nil, fmt.Errorf("media requires an ID or a link")
+	}
+
+	req := MediaMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             kind,
+	}
+
+	switch kind {
+	case "image":
+		media.Filename = ""
+		req.Image = &media
+	case "document":
+		req.Document = &media
+	case "audio":
+		media.Caption, media.Filename = "", ""
+		req.Audio = &media
+	case "video":
+		media.Filename = ""
+		req.Video = &media
+	default:
+		return nil, fmt.Errorf("unsupported media kind %q", kind)
+	}
+
+	return c.sendMessage(ctx, req)
+}
+
+// UploadMedia uploads raw bytes to the phone number's media store and returns
+// the media ID, which can then be referenced by MediaObject.ID. Uploaded media
+// is retained by Meta for 30 days.
+func (c *Client) UploadMedia(filename, mimeType string, data []byte) (string, error) {
+	return c.UploadMediaContext(context.Background(), filename, mimeType, data)
+}
+
+// UploadMediaContext is like UploadMedia but carries ctx.
+func (c *Client) UploadMediaContext(ctx context.Context, filename, mimeType string, data []byte) (string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	if err := mw.WriteField("messaging_product", "whatsapp"); err != nil {
+		return "", fmt.Errorf("write form field: %w", err)
+	}
+	if err := mw.WriteField("type", mimeType); err != nil {
+		return "", fmt.Errorf("write form field: %w", err)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	h.Set("Content-Type", mimeType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return "", fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("write form file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/media", c.getBaseURL(), c.PhoneNumberID)
+	respBody, err := c.do(ctx, "POST", url, mw.FormDataContentType(), buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("upload media: %w", err)
+	}
+
+	var result UploadMediaResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("unmarshal response: %w", err)
+	}
+	if result.ID == "" {
+		return "", fmt.Errorf("upload response missing media ID")
+	}
+
+	return result.ID, nil
+}
@@ -0,0 +1,204 @@
+package kapso
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMediaKind(t *testing.T) {
+	tests := []struct {
+		mime string
+		want string
+	}{
+		{"image/jpeg", "image"},
+		{"image/png", "image"},
+		{"IMAGE/PNG", "image"},
+		{"image/gif", "document"},
+		{"audio/ogg; codecs=opus", "audio"},
+		{"audio/mpeg", "audio"},
+		{"video/mp4", "video"},
+		{"application/pdf", "document"},
+		{"", "document"},
+	}
+	for _, tt := range tests {
+		if got := MediaKind(tt.mime); got != tt.want {
+			t.Errorf("MediaKind(%q) = %q, want %q", tt.mime, got, tt.want)
+		}
+	}
+}
+
+func TestSendMedia(t *testing.T) {
+	tests := []struct {
+		name    string
+		send    func(c *Client) (*SendMessageResponse, error)
+		kind    string
+		wantKey string // field expected inside the media object
+		dropKey string // field that must be stripped for this kind
+	}{
+		{
+			name: "image by link with caption",
+			send: func(c *Client) (*SendMessageResponse, error) {
+				return c.SendImage("+15551234567", MediaObject{Link: "https://example.com/a.jpg", Caption: "hi", Filename: "a.jpg"})
+			},
+			kind:    "image",
+			wantKey: "link",
+			dropKey: "filename",
+		},
+		{
+			name: "document by id with filename",
+			send: func(c *Client) (*SendMessageResponse, error) {
+				return c.SendDocument("+15551234567", MediaObject{ID: "media-1", Filename: "report.pdf"})
+			},
+			kind:    "document",
+			wantKey: "filename",
+		},
+		{
+			name: "audio drops caption",
+			send: func(c *Client) (*SendMessageResponse, error) {
+				return c.SendAudio("+15551234567", MediaObject{ID: "media-2", Caption: "ignored"})
+			},
+			kind:    "audio",
+			wantKey: "id",
+			dropKey: "caption",
+		},
+		{
+			name: "video by id",
+			send: func(c *Client) (*SendMessageResponse, error) {
+				return c.SendVideo("+15551234567", MediaObject{ID: "media-3", Caption: "clip"})
+			},
+			kind:    "video",
+			wantKey: "caption",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/12345/messages") {
+					t.Errorf("path = %q, want suffix /12345/messages", r.URL.Path)
+				}
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+				_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.out1"}]}`))
+			}))
+			defer srv.Close()
+
+			client := &Client{
+				APIKey:        "test-key",
+				PhoneNumberID: "12345",
+				HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+			}
+
+			resp, err := tt.send(client)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Messages) != 1 || resp.Messages[0].ID != "wamid.out1" {
+				t.Errorf("response messages = %+v, want wamid.out1", resp.Messages)
+			}
+
+			if payload["type"] != tt.kind {
+				t.Errorf("type = %v, want %q", payload["type"], tt.kind)
+			}
+			media, ok := payload[tt.kind].(map[string]interface{})
+			if !ok {
+				t.Fatalf("payload missing %q object: %v", tt.kind, payload)
+			}
+			if _, ok := media[tt.wantKey]; !ok {
+				t.Errorf("%s object missing %q: %v", tt.kind, tt.wantKey, media)
+			}
+			if tt.dropKey != "" {
+				if _, ok := media[tt.dropKey]; ok {
+					t.Errorf("%s object should not carry %q: %v", tt.kind, tt.dropKey, media)
+				}
+			}
+		})
+	}
+}
+
+func TestSendMediaRequiresReference(t *testing.T) {
+	client := &Client{APIKey: "k", PhoneNumberID: "1", HTTPClient: http.DefaultClient}
+	if _, err := client.SendImage("+1", MediaObject{Caption: "no media"}); err == nil {
+		t.Fatal("expected error when neither ID nor Link is set")
+	}
+}
+
+func TestUploadMedia(t *testing.T) {
+	t.Run("sends multipart form and returns ID", func(t *testing.T) {
+		var gotPath, gotProduct, gotType, gotFilename, gotPartType, gotData string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("parse multipart: %v", err)
+				return
+			}
+			gotProduct = r.FormValue("messaging_product")
+			gotType = r.FormValue("type")
+			f, hdr, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("form file: %v", err)
+				return
+			}
+			defer func() { _ = f.Close() }()
+			data, _ := io.ReadAll(f)
+			gotFilename = hdr.Filename
+			gotPartType = hdr.Header.Get("Content-Type")
+			gotData = string(data)
+			_, _ = w.Write([]byte(`{"id":"media-xyz"}`))
+		}))
+		defer srv.Close()
+
+		client := &Client{
+			APIKey:        "test-key",
+			PhoneNumberID: "12345",
+			HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		}
+
+		id, err := client.UploadMedia("report.pdf", "application/pdf", []byte("%PDF-1.4"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != "media-xyz" {
+			t.Errorf("id = %q, want %q", id, "media-xyz")
+		}
+		if !strings.HasSuffix(gotPath, "/12345/media") {
+			t.Errorf("path = %q, want suffix /12345/media", gotPath)
+		}
+		if gotProduct != "whatsapp" {
+			t.Errorf("messaging_product = %q, want whatsapp", gotProduct)
+		}
+		if gotType != "application/pdf" || gotPartType != "application/pdf" {
+			t.Errorf("type = %q, part Content-Type = %q, want application/pdf", gotType, gotPartType)
+		}
+		if gotFilename != "report.pdf" {
+			t.Errorf("filename = %q, want report.pdf", gotFilename)
+		}
+		if gotData != "%PDF-1.4" {
+			t.Errorf("file data = %q", gotData)
+		}
+	})
+
+	t.Run("returns error on non-200 status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad file"}}`))
+		}))
+		defer srv.Close()
+
+		client := &Client{
+			APIKey:        "test-key",
+			PhoneNumberID: "12345",
+			HTTPClient:    &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		}
+
+		_, err := client.UploadMedia("a.bin", "application/octet-stream", []byte("x"))
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Fatalf("error = %v, want status 400", err)
+		}
+	})
+}
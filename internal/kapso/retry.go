@@ -0,0 +1,123 @@
+package kapso
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryPolicy controls how the client retries retryable API errors. It
+// mirrors the transcription retry wrapper: exponential backoff with jitter,
+// except that a Retry-After header from the server replaces the computed delay.
+type retryPolicy struct {
+	attempts  int           // total tries; 0 or 1 disables retries
+	base      time.Duration // first backoff delay
+	factor    float64
+	jitter    float64
+	maxWait   time.Duration // give up instead of honouring a longer Retry-After
+	sleepFunc func(context.Context, time.Duration) error
+}
+
+// defaultRetryPolicy is used by NewClient.
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		attempts:  3,
+		base:      1 * time.Second,
+		factor:    2.0,
+		jitter:    0.25,
+		maxWait:   60 * time.Second,
+		sleepFunc: sleepContext,
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// do sends an API request and returns the body of a 200 or 201 response.
+// Other statuses become an *APIError; retryable ones are retried according to
+// the client's retry policy. Transport errors are not retried, since a send
+// may have reached the API before the connection failed.
+func (c *Client) do(ctx context.Context, method, url, contentType string, body []byte) ([]byte, error) {
+	p := c.retry
+	if p.sleepFunc == nil {
+		p.sleepFunc = sleepContext
+	}
+
+	delay := p.base
+	var lastErr error
+
+	for i := 0; i < max(p.attempts, 1); i++ {
+		respBody, err := c.doOnce(ctx, method, url, contentType, body)
+		if err == nil {
+			return respBody, nil
+		}
+		lastErr = err
+
+		if !IsRetryable(err) || i >= p.attempts-1 {
+			break
+		}
+
+		wait := delay + time.Duration(float64(delay)*p.jitter*rand.Float64()) //nolint:gosec
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > p.maxWait {
+				break
+			}
+			wait = apiErr.RetryAfter
+		}
+		if err := p.sleepFunc(ctx, wait); err != nil {
+			return nil, err
+		}
+		delay = time.Duration(float64(delay) * p.factor)
+	}
+
+	return nil, lastErr
+}
+
+// doOnce performs a single API request.
+func (c *Client) doOnce(ctx context.Context, method, url, contentType string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-API-Key", c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError(resp, respBody)
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,190 @@
+package kapso
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  APIError
+		want bool
+	}{
+		{"429", APIError{StatusCode: 429}, true},
+		{"500", APIError{StatusCode: 500}, true},
+		{"503", APIError{StatusCode: 503}, true},
+		{"400", APIError{StatusCode: 400}, false},
+		{"401", APIError{StatusCode: 401}, false},
+		{"400 pair rate limit", APIError{StatusCode: 400, Code: 131056}, true},
+		{"400 throughput", APIError{StatusCode: 400, Code: 130429}, true},
+		{"400 re-engagement window", APIError{StatusCode: 400, Code: 131047}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Retryable(); got != tt.want {
+				t.Errorf("Retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("seconds: got %v, want 7s", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("empty: got %v, want 0", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("garbage: got %v, want 0", got)
+	}
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 20*time.Second || got > 30*time.Second {
+		t.Errorf("HTTP date: got %v, want ~30s", got)
+	}
+}
+
+// retryServer answers with the given statuses in order, then 200.
+func retryServer(t *testing.T, statuses []int, header http.Header, body string) (*Client, *int, func()) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := calls
+		calls++
+		if i < len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[i])
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.ok"}]}`))
+	}))
+	client := NewClient("test-key", "12345")
+	client.HTTPClient = &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}}
+	return client, &calls, srv.Close
+}
+
+func TestClientRetries(t *testing.T) {
+	t.Run("retries 5xx with backoff then succeeds", func(t *testing.T) {
+		client, calls, done := retryServer(t, []int{502, 503}, nil, "bad gateway")
+		defer done()
+		var slept []time.Duration
+		client.retry.sleepFunc = func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+
+		resp, err := client.SendText("+1", "hi")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Messages[0].ID != "wamid.ok" {
+			t.Errorf("ID = %q, want wamid.ok", resp.Messages[0].ID)
+		}
+		if *calls != 3 {
+			t.Errorf("calls = %d, want 3", *calls)
+		}
+		if len(slept) != 2 || slept[0] < time.Second || slept[1] < 2*time.Second {
+			t.Errorf("backoff delays = %v, want ~[1s 2s]", slept)
+		}
+	})
+
+	t.Run("honours Retry-After on 429", func(t *testing.T) {
+		client, calls, done := retryServer(t, []int{429}, http.Header{"Retry-After": {"5"}}, "slow down")
+		defer done()
+		var slept []time.Duration
+		client.retry.sleepFunc = func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+
+		if _, err := client.SendText("+1", "hi"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *calls != 2 {
+			t.Errorf("calls = %d, want 2", *calls)
+		}
+		if len(slept) != 1 || slept[0] != 5*time.Second {
+			t.Errorf("slept %v, want [5s]", slept)
+		}
+	})
+
+	t.Run("gives up when Retry-After exceeds the limit", func(t *testing.T) {
+		client, calls, done := retryServer(t, []int{429}, http.Header{"Retry-After": {"3600"}}, "slow down")
+		defer done()
+		client.retry.sleepFunc = func(context.Context, time.Duration) error {
+			t.Error("should not sleep")
+			return nil
+		}
+
+		_, err := client.SendText("+1", "hi")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Hour {
+			t.Fatalf("error = %v, want *APIError with RetryAfter 1h", err)
+		}
+		if *calls != 1 {
+			t.Errorf("calls = %d, want 1", *calls)
+		}
+	})
+
+	t.Run("does not retry client errors and parses Meta error", func(t *testing.T) {
+		body := `{"error":{"message":"Re-engagement message","code":131047,"error_subcode":2494010}}`
+		client, calls, done := retryServer(t, []int{400, 400}, nil, body)
+		defer done()
+
+		_, err := client.SendText("+1", "hi")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("error = %v, want *APIError", err)
+		}
+		if apiErr.StatusCode != 400 || apiErr.Code != 131047 || apiErr.Subcode != 2494010 || apiErr.Message != "Re-engagement message" {
+			t.Errorf("APIError = %+v", apiErr)
+		}
+		if IsRetryable(err) {
+			t.Error("IsRetryable() = true, want false")
+		}
+		if *calls != 1 {
+			t.Errorf("calls = %d, want 1", *calls)
+		}
+	})
+
+	t.Run("stops when context is cancelled while waiting", func(t *testing.T) {
+		client, calls, done := retryServer(t, []int{503, 503, 503}, nil, "unavailable")
+		defer done()
+		client.retry.base = time.Hour
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		_, err := client.SendTextContext(ctx, "+1", "hi")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled", err)
+		}
+		if *calls != 1 {
+			t.Errorf("calls = %d, want 1", *calls)
+		}
+	})
+
+	t.Run("zero-value client does not retry", func(t *testing.T) {
+		client, calls, done := retryServer(t, []int{503}, nil, "unavailable")
+		defer done()
+		client.retry = retryPolicy{}
+
+		if _, err := client.SendText("+1", "hi"); !IsRetryable(err) {
+			t.Fatalf("error = %v, want retryable *APIError", err)
+		}
+		if *calls != 1 {
+			t.Errorf("calls = %d, want 1", *calls)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package kapso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// TemplateMessageRequest is the payload for sending an approved message template.
+type TemplateMessageRequest struct {
+	MessagingProduct string   `json:"messaging_product"`
+	RecipientType    string   `json:"recipient_type"`
+	To               string   `json:"to"`
+	Type             string   `json:"type"`
+	Template         Template `json:"template"`
+}
+
+// Template identifies an approved template and carries its parameters.
+type Template struct {
+	Name       string              `json:"name"`
+	Language   TemplateLanguage    `json:"language"`
+	Components []TemplateComponent `json:"components,omitempty"`
+}
+
+// TemplateLanguage is the locale the template was approved in (e.g. "en_US").
+type TemplateLanguage struct {
+	Code string `json:"code"`
+}
+
+// TemplateComponent fills the variables of one template component.
+// Type is "header", "body" or "button". Buttons also need SubType
+// ("quick_reply" or "url") and the zero-based Index of the button.
+type TemplateComponent struct {
+	Type       string              `json:"type"`
+	SubType    string              `json:"sub_type,omitempty"`
+	Index      string              `json:"index,omitempty"`
+	Parameters []TemplateParameter `json:"parameters"`
+}
+
+// TemplateParameter is a single value substituted into a template placeholder.
+// Type selects which of the value fields is used.
+type TemplateParameter struct {
+	Type          string            `json:"type"` // text, currency, date_time, image, document, video, payload
+	ParameterName string            `json:"parameter_name,omitempty"`
+	Text          string            `json:"text,omitempty"`
+	Payload       string            `json:"payload,omitempty"`
+	Currency      *TemplateCurrency `json:"currency,omitempty"`
+	DateTime      *TemplateDateTime `json:"date_time,omitempty"`
+	Image         *MediaObject      `json:"image,omitempty"`
+	Document      *MediaObject      `json:"document,omitempty"`
+	Video         *MediaObject      `json:"video,omitempty"`
+}
+
+// TemplateCurrency is a localized currency parameter.
+type TemplateCurrency struct {
+	FallbackValue string `json:"fallback_value"`
+	Code          string `json:"code"`
+	Amount1000    int64  `json:"amount_1000"`
+}
+
+// TemplateDateTime is a date/time parameter rendered as FallbackValue.
+type TemplateDateTime struct {
+	FallbackValue string `json:"fallback_value"`
+}
+
+// TextParameter returns a text parameter for a {{n}} placeholder.
+func TextParameter(text string) TemplateParameter {
+	return TemplateParameter{Type: "text", Text: text}
+}
+
+// PayloadParameter returns the payload echoed back when a quick-reply button is tapped.
+func PayloadParameter(payload string) TemplateParameter {
+	return TemplateParameter{Type: "payload", Payload: payload}
+}
+
+// MediaParameter returns an image, document or video header parameter.
+func MediaParameter(kind string, media MediaObject) TemplateParameter {
+	p := TemplateParameter{Type: kind}
+	switch kind {
+	case "image":
+		p.Image = &media
+	case "document":
+		p.Document = &media
+	case "video":
+		p.Video = &media
+	}
+	return p
+}
+
+// HeaderComponent fills the header variables of a template.
+func HeaderComponent(params ...TemplateParameter) TemplateComponent {
+	return TemplateComponent{Type: "header", Parameters: params}
+}
+
+// BodyComponent fills the body variables of a template, in placeholder order.
+func BodyComponent(params ...TemplateParameter) TemplateComponent {
+	return TemplateComponent{Type: "body", Parameters: params}
+}
+
+// ButtonComponent fills the variables of the button at index.
+// subType is "quick_reply" (payload parameter) or "url" (text parameter).
+func ButtonComponent(subType string, index int, params ...TemplateParameter) TemplateComponent {
+	return TemplateComponent{
+		Type:       "button",
+		SubType:    subType,
+		Index:      strconv.Itoa(index),
+		Parameters: params,
+	}
+}
+
+// SendTemplate sends an approved template. Templates are the only way to
+// start a conversation outside the 24-hour customer service window.
+func (c *Client) SendTemplate(to, name, language string, components []TemplateComponent) (*SendMessageResponse, error) {
+	return c.SendTemplateContext(context.Background(), to, name, language, components)
+}
+
+// SendTemplateContext is like SendTemplate but carries ctx.
+func (c *Client) SendTemplateContext(ctx context.Context, to, name, language string, components []TemplateComponent) (*SendMessageResponse, error) {
+	if name == "" || language == "" {
+		return nil, fmt.Errorf("template name and language are required")
+	}
+	return c.sendMessage(ctx, TemplateMessageRequest{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             "template",
+		Template: Template{
+			Name:       name,
+			Language:   TemplateLanguage{Code: language},
+			Components: components,
+		},
+	})
+}
+
+// ListTemplatesParams are query parameters for listing message templates.
+type ListTemplatesParams struct {
+	Name   string // filter by (partial) template name
+	Status string // e.g. "APPROVED"
+	Limit  int
+	After  string // pagination cursor
+}
+
+// ListTemplatesResponse is the response from the message templates API.
+type ListTemplatesResponse struct {
+	Data   []TemplateInfo `json:"data"`
+	Paging *Paging        `json:"paging,omitempty"`
+}
+
+// TemplateInfo describes a template registered on the business account.
+type TemplateInfo struct {
+	ID         string                  `json:"id"`
+	Name       string                  `json:"name"`
+	Language   string                  `json:"language"`
+	Status     string                  `json:"status"`
+	Category   string                  `json:"category"`
+	Components []TemplateInfoComponent `json:"components"`
+}
+
+// TemplateInfoComponent is a component definition of a registered template.
+type TemplateInfoComponent struct {
+	Type    string `json:"type"`             // HEADER, BODY, FOOTER, BUTTONS
+	Format  string `json:"format,omitempty"` // header format: TEXT, IMAGE, DOCUMENT, VIDEO
+	Text    string `json:"text,omitempty"`
+	Buttons []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+		URL  string `json:"url,omitempty"`
+	} `json:"buttons,omitempty"`
+}
+
+// Body returns the body text of the template, with {{n}} placeholders intact.
+func (t TemplateInfo) Body() string {
+	for _, comp := range t.Components {
+		if comp.Type == "BODY" {
+			return comp.Text
+		}
+	}
+	return ""
+}
+
+// ListTemplates fetches the message templates of the WhatsApp Business
+// Account. Templates belong to the account, not the phone number, so
+// BusinessAccountID must be set.
+func (c *Client) ListTemplates(params ListTemplatesParams) (*ListTemplatesResponse, error) {
+	return c.ListTemplatesContext(context.Background(), params)
+}
+
+// ListTemplatesContext is like ListTemplates but carries ctx.
+func (c *Client) ListTemplatesContext(ctx context.Context, params ListTemplatesParams) (*ListTemplatesResponse, error) {
+	if c.BusinessAccountID == "" {
+		return nil, fmt.Errorf("business account ID is required to list templates")
+	}
+
+	u, err := url.Parse(fmt.Sprintf("%s/%s/message_templates", c.getBaseURL(), c.BusinessAccountID))
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+
+	q := u.Query()
+	if params.Name != "" {
+		q.Set("name", params.Name)
+	}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Limit > 0 {
+		q.Set("limit", fmt.Sprintf("%d", params.Limit))
+	}
+	if params.After != "" {
+		q.Set("after", params.After)
+	}
+	u.RawQuery = q.Encode()
+
+	body, err := c.do(ctx, "GET", u.String(), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+
+	var result ListTemplatesResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return &result, nil
+}
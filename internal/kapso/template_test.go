@@ -0,0 +1,110 @@
+package kapso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendTemplate(t *testing.T) {
+	var payload map[string]interface{}
+	client, done := captureClient(t, &payload)
+	defer done()
+
+	_, err := client.SendTemplate("+15551234567", "appointment_reminder", "en_US", []TemplateComponent{
+		HeaderComponent(MediaParameter("image", MediaObject{Link: "https://example.com/logo.png"})),
+		BodyComponent(TextParameter("Ana"), TextParameter("Tuesday 10:00")),
+		ButtonComponent("quick_reply", 0, PayloadParameter("CONFIRM")),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if payload["type"] != "template" {
+		t.Errorf("type = %v, want template", payload["type"])
+	}
+	tpl := payload["template"].(map[string]interface{})
+	if tpl["name"] != "appointment_reminder" {
+		t.Errorf("name = %v", tpl["name"])
+	}
+	if lang := tpl["language"].(map[string]interface{}); lang["code"] != "en_US" {
+		t.Errorf("language = %v", lang)
+	}
+
+	comps := tpl["components"].([]interface{})
+	if len(comps) != 3 {
+		t.Fatalf("got %d components, want 3", len(comps))
+	}
+
+	header := comps[0].(map[string]interface{})
+	hp := header["parameters"].([]interface{})[0].(map[string]interface{})
+	if hp["type"] != "image" || hp["image"].(map[string]interface{})["link"] != "https://example.com/logo.png" {
+		t.Errorf("header parameter = %v", hp)
+	}
+
+	body := comps[1].(map[string]interface{})
+	bp := body["parameters"].([]interface{})
+	if len(bp) != 2 || bp[1].(map[string]interface{})["text"] != "Tuesday 10:00" {
+		t.Errorf("body parameters = %v", bp)
+	}
+
+	button := comps[2].(map[string]interface{})
+	if button["sub_type"] != "quick_reply" || button["index"] != "0" {
+		t.Errorf("button component = %v", button)
+	}
+}
+
+func TestSendTemplateRequiresNameAndLanguage(t *testing.T) {
+	client := &Client{APIKey: "k", PhoneNumberID: "1", HTTPClient: http.DefaultClient}
+	if _, err := client.SendTemplate("+1", "", "en_US", nil); err == nil {
+		t.Error("expected error for missing name")
+	}
+	if _, err := client.SendTemplate("+1", "hello_world", "", nil); err == nil {
+		t.Error("expected error for missing language")
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	t.Run("queries business account and parses templates", func(t *testing.T) {
+		var gotPath, gotStatus string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			gotStatus = r.URL.Query().Get("status")
+			_, _ = w.Write([]byte(`{"data":[{"id":"1","name":"hello_world","language":"en_US","status":"APPROVED","category":"UTILITY",
+				"components":[{"type":"HEADER","format":"TEXT","text":"Hi"},{"type":"BODY","text":"Hello {{1}}"}]}]}`))
+		}))
+		defer srv.Close()
+
+		client := &Client{
+			APIKey:            "test-key",
+			PhoneNumberID:     "12345",
+			BusinessAccountID: "waba-1",
+			HTTPClient:        &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		}
+
+		resp, err := client.ListTemplates(ListTemplatesParams{Status: "APPROVED"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(gotPath, "/waba-1/message_templates") {
+			t.Errorf("path = %q, want suffix /waba-1/message_templates", gotPath)
+		}
+		if gotStatus != "APPROVED" {
+			t.Errorf("status query = %q, want APPROVED", gotStatus)
+		}
+		if len(resp.Data) != 1 || resp.Data[0].Name != "hello_world" {
+			t.Fatalf("data = %+v", resp.Data)
+		}
+		if body := resp.Data[0].Body(); body != "Hello {{1}}" {
+			t.Errorf("Body() = %q, want %q", body, "Hello {{1}}")
+		}
+	})
+
+	t.Run("requires business account ID", func(t *testing.T) {
+		client := &Client{APIKey: "k", PhoneNumberID: "1", HTTPClient: http.DefaultClient}
+		if _, err := client.ListTemplates(ListTemplatesParams{}); err == nil {
+			t.Fatal("expected error without business account ID")
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package kapso
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimestamp reads a message timestamp given as Unix seconds (WhatsApp),
+// Unix milliseconds (OpenClaw history) or RFC 3339 (Kapso's API). It returns
+// the zero time when s is none of those.
+func ParseTimestamp(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case n <= 0:
+			return time.Time{}
+		case n >= 1e11: // past year 5000 as seconds, so milliseconds
+			return time.UnixMilli(n).UTC()
+		default:
+			return time.Unix(n, 0).UTC()
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,31 @@
+package kapso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"1700000000", time.Unix(1700000000, 0)},
+		{" 1700000000 ", time.Unix(1700000000, 0)},
+		{"1700000000123", time.UnixMilli(1700000000123)},
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02T05:04:05+02:00", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"0", time.Time{}},
+		{"yesterday", time.Time{}},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		got := ParseTimestamp(tt.in)
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !got.IsZero() && got.Location() != time.UTC {
+			t.Errorf("ParseTimestamp(%q) location = %v, want UTC", tt.in, got.Location())
+		}
+	}
+}
@@ -69,18 +69,32 @@ type ContactProfile struct {
 // The Kapso field contains enrichment metadata provided by both the polling
 // list API and the webhook API (media URLs, server-side transcripts, etc.).
 type Message struct {
-	From      string           `json:"from"`
-	ID        string           `json:"id"`
-	Timestamp string           `json:"timestamp"`
-	Type      string           `json:"type"`
-	Text      *TextContent     `json:"text,omitempty"`
-	Image     *ImageContent    `json:"image,omitempty"`
-	Document  *DocumentContent `json:"document,omitempty"`
-	Audio     *AudioContent    `json:"audio,omitempty"`
-	Video     *VideoContent    `json:"video,omitempty"`
-	Sticker   *StickerContent  `json:"sticker,omitempty"`
-	Location  *LocationContent `json:"location,omitempty"`
-	Kapso     *KapsoMeta       `json:"kapso,omitempty"`
+	From        string              `json:"from"`
+	ID          string              `json:"id"`
+	Timestamp   string              `json:"timestamp"`
+	Type        string              `json:"type"`
+	Text        *TextContent        `json:"text,omitempty"`
+	Image       *ImageContent       `json:"image,omitempty"`
+	Document    *DocumentContent    `json:"document,omitempty"`
+	Audio       *AudioContent       `json:"audio,omitempty"`
+	Video       *VideoContent       `json:"video,omitempty"`
+	Sticker     *StickerContent     `json:"sticker,omitempty"`
+	Location    *LocationContent    `json:"location,omitempty"`
+	Contacts    []ContactCard       `json:"contacts,omitempty"`
+	Interactive *InteractiveContent `json:"interactive,omitempty"`
+	Button      *ButtonContent      `json:"button,omitempty"`
+	Reaction    *ReactionContent    `json:"reaction,omitempty"`
+	Context     *MessageContext     `json:"context,omitempty"`
+	Errors      []StatusError       `json:"errors,omitempty"` // set on failed-status events
+	Kapso       *KapsoMeta          `json:"kapso,omitempty"`
+}
+
+// MessageContext is set when the user swiped to reply to an earlier message.
+// ID is the quoted message; From is its sender (the business number when the
+// user quoted one of our replies).
+type MessageContext struct {
+	From string `json:"from,omitempty"`
+	ID   string `json:"id"`
 }
 
 // KapsoMeta contains Kapso-enhanced metadata present in both polling and
@@ -160,21 +174,73 @@ type LocationContent struct {
 	Address   string  `json:"address,omitempty"`
 }
 
-// Status represents a message delivery status update.
+// InteractiveContent holds the user's answer to an interactive message.
+// Type is "button_reply" or "list_reply"; the matching field is set.
+type InteractiveContent struct {
+	Type        string       `json:"type"`
+	ButtonReply *ButtonReply `json:"button_reply,omitempty"`
+	ListReply   *ListRow     `json:"list_reply,omitempty"`
+}
+
+// ButtonContent holds a tap on a template quick-reply button (type "button").
+type ButtonContent struct {
+	Payload string `json:"payload"`
+	Text    string `json:"text"`
+}
+
+// ReactionContent is an emoji reaction to an earlier message. An empty Emoji
+// means a previous reaction was removed.
+type ReactionContent struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// Status represents a message delivery status update ("sent", "delivered",
+// "read" or "failed"). Errors explains a failure.
 type Status struct {
-	ID          string `json:"id"`
-	Status      string `json:"status"`
-	Timestamp   string `json:"timestamp"`
-	RecipientID string `json:"recipient_id"`
+	ID          string        `json:"id"`
+	Status      string        `json:"status"`
+	Timestamp   string        `json:"timestamp"`
+	RecipientID string        `json:"recipient_id"`
+	Errors      []StatusError `json:"errors,omitempty"`
+}
+
+// StatusError is a Meta error attached to a failed status, e.g. code 131047
+// (re-engagement window expired) or 131026 (undeliverable).
+type StatusError struct {
+	Code      int              `json:"code"`
+	Title     string           `json:"title"`
+	Message   string           `json:"message,omitempty"`
+	ErrorData *StatusErrorData `json:"error_data,omitempty"`
+}
+
+// StatusErrorData carries Meta's human-readable failure details.
+type StatusErrorData struct {
+	Details string `json:"details"`
 }
 
 // SendMessageRequest is the payload for sending a text message via Kapso.
 type SendMessageRequest struct {
-	MessagingProduct string      `json:"messaging_product"`
-	RecipientType    string      `json:"recipient_type"`
-	To               string      `json:"to"`
-	Type             string      `json:"type"`
-	Text             TextContent `json:"text"`
+	MessagingProduct string        `json:"messaging_product"`
+	RecipientType    string        `json:"recipient_type"`
+	To               string        `json:"to"`
+	Type             string        `json:"type"`
+	Text             TextContent   `json:"text"`
+	Context          *ReplyContext `json:"context,omitempty"`
+}
+
+// ReplyContext makes an outbound message a quoted reply to MessageID.
+type ReplyContext struct {
+	MessageID string `json:"message_id"`
+}
+
+// ReactionMessageRequest is the payload for reacting to a message via Kapso.
+type ReactionMessageRequest struct {
+	MessagingProduct string          `json:"messaging_product"`
+	RecipientType    string          `json:"recipient_type"`
+	To               string          `json:"to"`
+	Type             string          `json:"type"`
+	Reaction         ReactionContent `json:"reaction"`
 }
 
 // MarkReadRequest is the payload for marking a message as read via Kapso.
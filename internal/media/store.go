@@ -0,0 +1,192 @@
+// Package media stores inbound WhatsApp attachments on local disk so the
+// agent can open them. Kapso media URLs require the API key and expire, so
+// they are useless to the agent on their own.
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+)
+
+// allowedTypes maps accepted MIME types to the file extension used on disk.
+var allowedTypes = map[string]string{
+	"image/jpeg":                    ".jpg",
+	"image/png":                     ".png",
+	"image/webp":                    ".webp",
+	"audio/aac":                     ".aac",
+	"audio/amr":                     ".amr",
+	"audio/mpeg":                    ".mp3",
+	"audio/mp4":                     ".m4a",
+	"audio/ogg":                     ".ogg",
+	"video/mp4":                     ".mp4",
+	"video/3gpp":                    ".3gp",
+	"application/pdf":               ".pdf",
+	"text/plain":                    ".txt",
+	"text/csv":                      ".csv",
+	"application/msword":            ".doc",
+	"application/vnd.ms-excel":      ".xls",
+	"application/vnd.ms-powerpoint": ".ppt",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+}
+
+// Store downloads attachments into Dir, named by the SHA-256 of their
+// content so the same file is only kept once.
+type Store struct {
+	Dir       string
+	Client    *kapso.Client
+	MaxSize   int64         // maximum attachment size in bytes
+	Retention time.Duration // files unused for longer are removed by Cleanup; 0 = keep forever
+
+	now func() time.Time
+}
+
+// Save downloads the attachment at rawURL (through the client's SSRF guard
+// and MaxSize limit), checks that its content matches the declared MIME type,
+// and returns the absolute path of the stored file.
+func (s *Store) Save(ctx context.Context, rawURL, mimeType string) (string, error) {
+	declared := baseType(mimeType)
+	ext, ok := allowedTypes[declared]
+	if !ok {
+		return "", fmt.Errorf("media type %q is not allowed", mimeType)
+	}
+
+	data, err := s.Client.DownloadMediaContext(ctx, rawURL, s.MaxSize)
+	if err != nil {
+		return "", err
+	}
+	if err := checkContent(declared, data); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("create media dir: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	path := filepath.Join(s.Dir, hex.EncodeToString(sum[:])+ext)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	// Already stored: refresh its age so retention counts from the last use.
+	if _, err := os.Stat(path); err == nil {
+		now := s.clock()
+		_ = os.Chtimes(path, now, now)
+		return path, nil
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("write media: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("write media: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("store media: %w", err)
+	}
+
+	return path, nil
+}
+
+// Cleanup removes stored files that have not been used within Retention and
+// returns how many were removed.
+func (s *Store) Cleanup() (int, error) {
+	if s.Retention <= 0 {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read media dir: %w", err)
+	}
+
+	cutoff := s.clock().Add(-s.Retention)
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.Dir, e.Name())); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// StartCleanup runs Cleanup every interval until ctx is cancelled.
+func (s *Store) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Cleanup()
+			if err != nil {
+				log.Printf("media: cleanup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("media: removed %d expired file(s)", n)
+			}
+		}
+	}
+}
+
+// FileURL returns a file:// reference for a stored path.
+func FileURL(path string) string {
+	return "file://" + filepath.ToSlash(path)
+}
+
+// checkContent sniffs data and rejects content that contradicts the declared
+// type: images must sniff as images, and nothing may sniff as HTML.
+func checkContent(declared string, data []byte) error {
+	sniffed := baseType(http.DetectContentType(data))
+	if sniffed == "text/html" || sniffed == "text/xml" {
+		return fmt.Errorf("media declared as %s looks like %s", declared, sniffed)
+	}
+	if strings.HasPrefix(declared, "image/") && sniffed != declared {
+		return fmt.Errorf("media declared as %s looks like %s", declared, sniffed)
+	}
+	return nil
+}
+
+// baseType lowercases a MIME type and strips its parameters.
+func baseType(mimeType string) string {
+	mt := strings.ToLower(strings.TrimSpace(mimeType))
+	if i := strings.Index(mt, ";"); i >= 0 {
+		mt = strings.TrimSpace(mt[:i])
+	}
+	return mt
+}
+
+func (s *Store) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
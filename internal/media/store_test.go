@@ -0,0 +1,133 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+)
+
+// rewriteTransport rewrites all request URLs to point at the test server.
+type rewriteTransport struct {
+	base    string
+	wrapped http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(t.base, "http://")
+	return t.wrapped.RoundTrip(req)
+}
+
+// pngData is a minimal PNG header, enough for content sniffing.
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+const mediaURL = "https://lookaside.fbcdn.net/media/123"
+
+func newTestStore(t *testing.T, body []byte) *Store {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return &Store{
+		Dir: filepath.Join(t.TempDir(), "media"),
+		Client: &kapso.Client{
+			APIKey:     "test-key",
+			HTTPClient: &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		},
+		MaxSize:   1024,
+		Retention: time.Hour,
+	}
+}
+
+func TestSave(t *testing.T) {
+	s := newTestStore(t, pngData)
+
+	path, err := s.Save(context.Background(), mediaURL, "image/png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256(pngData)
+	if want := hex.EncodeToString(sum[:]) + ".png"; filepath.Base(path) != want {
+		t.Errorf("file name = %q, want %q", filepath.Base(path), want)
+	}
+	if !filepath.IsAbs(path) {
+		t.Errorf("path %q is not absolute", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(pngData) {
+		t.Fatalf("stored data = %q, err = %v", data, err)
+	}
+
+	// Same content again: same file, no duplicate.
+	again, err := s.Save(context.Background(), mediaURL, "image/png")
+	if err != nil || again != path {
+		t.Errorf("second Save = %q, %v; want %q", again, err, path)
+	}
+	entries, _ := os.ReadDir(s.Dir)
+	if len(entries) != 1 {
+		t.Errorf("media dir has %d entries, want 1", len(entries))
+	}
+}
+
+func TestSaveRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		mimeType string
+		url      string
+	}{
+		{"disallowed type", []byte("MZ\x90\x00"), "application/x-msdownload", mediaURL},
+		{"image that is HTML", []byte("<html><script>alert(1)</script></html>"), "image/jpeg", mediaURL},
+		{"document that is HTML", []byte("<!DOCTYPE html><html></html>"), "application/pdf", mediaURL},
+		{"image mismatch", pngData, "image/jpeg", mediaURL},
+		{"host outside allowlist", pngData, "image/png", "https://evil.example.com/a.png"},
+		{"larger than MaxSize", append(append([]byte{}, pngData...), make([]byte, 2048)...), "image/png", mediaURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t, tt.body)
+			if path, err := s.Save(context.Background(), tt.url, tt.mimeType); err == nil {
+				t.Fatalf("expected error, stored %q", path)
+			}
+			if entries, _ := os.ReadDir(s.Dir); len(entries) != 0 {
+				t.Errorf("media dir has %d entries after rejection", len(entries))
+			}
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	s := newTestStore(t, pngData)
+	path, err := s.Save(context.Background(), mediaURL, "image/png")
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Fresh file survives.
+	if n, err := s.Cleanup(); err != nil || n != 0 {
+		t.Fatalf("Cleanup() = %d, %v; want 0, nil", n, err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Cleanup(); err != nil || n != 1 {
+		t.Fatalf("Cleanup() = %d, %v; want 1, nil", n, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expired file still present: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package outbound paces messages the bridge sends to WhatsApp so bursts of
+// replies stay within Meta's per-number throughput and pair-rate limits.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SendFunc performs one outbound API call (one WhatsApp message).
+type SendFunc func(ctx context.Context) error
+
+// Queue is the bridge's central outbound dispatcher. Each recipient has a FIFO
+// lane drained by its own goroutine, so messages to one user go out in order
+// and at least Interval apart, while a global limiter caps the number of
+// messages per second across all recipients. The zero value sends without
+// pacing.
+type Queue struct {
+	Rate     float64       // messages per second across all recipients; 0 = unlimited
+	Interval time.Duration // minimum gap between messages to one recipient
+
+	mu    sync.Mutex
+	lanes map[string]*lane
+	next  time.Time // earliest start of the next message (global limiter)
+	depth int       // messages queued or in flight
+	swept time.Time // last sweep for idle lanes
+
+	now       func() time.Time
+	sleepFunc func(context.Context, time.Duration) error
+}
+
+// lane is the FIFO of pending batches for one recipient.
+type lane struct {
+	batches []*batch
+	running bool
+	last    time.Time // when the previous message to this recipient was sent
+}
+
+// batch is one Send call; its messages go out back to back.
+type batch struct {
+	ctx   context.Context
+	sends []SendFunc
+	done  chan error
+}
+
+// Send queues sends for recipient to and waits until they have all run, or
+// until ctx is done. The sends of one call are never interleaved with other
+// messages to the same recipient, which keeps multi-chunk replies in order.
+// Once one send fails, the rest of the batch is skipped. The returned error
+// joins the send error with any context error.
+func (q *Queue) Send(ctx context.Context, to string, sends ...SendFunc) error {
+	if len(sends) == 0 {
+		return nil
+	}
+
+	b := &batch{ctx: ctx, sends: sends, done: make(chan error, 1)}
+
+	q.mu.Lock()
+	if q.lanes == nil {
+		q.lanes = make(map[string]*lane)
+	}
+	q.prune()
+	l := q.lanes[to]
+	if l == nil {
+		l = &lane{}
+		q.lanes[to] = l
+	}
+	l.batches = append(l.batches, b)
+	q.depth += len(sends)
+	if !l.running {
+		l.running = true
+		go q.drain(to, l)
+	}
+	q.mu.Unlock()
+
+	select {
+	case err := <-b.done:
+		return err
+	case <-ctx.Done():
+		// The lane skips the remaining sends once it sees the cancelled ctx.
+		return ctx.Err()
+	}
+}
+
+// Depth returns the number of messages queued or being sent. A steadily
+// growing depth means replies are produced faster than the limits allow.
+func (q *Queue) Depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.depth
+}
+
+// drain runs the batches of one lane until it is empty.
+func (q *Queue) drain(to string, l *lane) {
+	for {
+		q.mu.Lock()
+		if len(l.batches) == 0 {
+			l.running = false
+			// Without an interval there is nothing to remember; otherwise
+			// the lane is pruned by a later Send once its interval passes.
+			if q.Interval <= 0 {
+				delete(q.lanes, to)
+			}
+			q.mu.Unlock()
+			return
+		}
+		b := l.batches[0]
+		l.batches = l.batches[1:]
+		q.mu.Unlock()
+
+		b.done <- q.run(l, b)
+	}
+}
+
+// prune forgets idle recipients whose pacing interval has passed, so the lane
+// map only holds recipients that were messaged recently. It sweeps at most
+// once per Interval. The caller must hold q.mu.
+func (q *Queue) prune() {
+	now := q.clock()
+	if now.Sub(q.swept) < q.Interval {
+		return
+	}
+	q.swept = now
+	for to, l := range q.lanes {
+		if !l.running && now.Sub(l.last) >= q.Interval {
+			delete(q.lanes, to)
+		}
+	}
+}
+
+// run sends the messages of one batch, pacing each against the recipient
+// interval and the global rate.
+func (q *Queue) run(l *lane, b *batch) error {
+	var errs []error
+	for _, send := range b.sends {
+		if len(errs) == 0 {
+			err := q.wait(b.ctx, l)
+			if err == nil {
+				err = send(b.ctx)
+				q.mu.Lock()
+				l.last = q.clock()
+				q.mu.Unlock()
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		q.mu.Lock()
+		q.depth--
+		q.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// wait blocks until the recipient interval has elapsed and a global slot is
+// free, reserving that slot.
+func (q *Queue) wait(ctx context.Context, l *lane) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	now := q.clock()
+	start := now
+	if q.Interval > 0 && !l.last.IsZero() {
+		if t := l.last.Add(q.Interval); t.After(start) {
+			start = t
+		}
+	}
+	if q.Rate > 0 {
+		if q.next.After(start) {
+			start = q.next
+		}
+		q.next = start.Add(time.Duration(float64(time.Second) / q.Rate))
+	}
+	q.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		return q.sleep(ctx, d)
+	}
+	return nil
+}
+
+func (q *Queue) clock() time.Time {
+	if q.now != nil {
+		return q.now()
+	}
+	return time.Now()
+}
+
+func (q *Queue) sleep(ctx context.Context, d time.Duration) error {
+	if q.sleepFunc != nil {
+		return q.sleepFunc(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
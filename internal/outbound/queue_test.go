@@ -0,0 +1,230 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the order in which sends run.
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) send(label string) SendFunc {
+	return func(context.Context) error {
+		r.mu.Lock()
+		r.sent = append(r.sent, label)
+		r.mu.Unlock()
+		return nil
+	}
+}
+
+func TestSendKeepsBatchOrder(t *testing.T) {
+	q := &Queue{}
+	r := &recorder{}
+
+	var wg sync.WaitGroup
+	for b := 0; b < 5; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			var sends []SendFunc
+			for c := 0; c < 3; c++ {
+				sends = append(sends, r.send(fmt.Sprintf("%d-%d", b, c)))
+			}
+			if err := q.Send(context.Background(), "+1", sends...); err != nil {
+				t.Errorf("Send: %v", err)
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	if len(r.sent) != 15 {
+		t.Fatalf("sent %d messages, want 15", len(r.sent))
+	}
+	// Chunks of one batch must be contiguous and in order.
+	for i := 0; i < len(r.sent); i += 3 {
+		var b int
+		_, _ = fmt.Sscanf(r.sent[i], "%d-", &b)
+		for c := 0; c < 3; c++ {
+			if want := fmt.Sprintf("%d-%d", b, c); r.sent[i+c] != want {
+				t.Fatalf("sent = %v, batch %d interleaved", r.sent, b)
+			}
+		}
+	}
+	if d := q.Depth(); d != 0 {
+		t.Errorf("Depth() = %d after completion, want 0", d)
+	}
+}
+
+func TestSendPacesRecipientAndGlobalRate(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var mu sync.Mutex
+	now := base
+	var waits []time.Duration
+
+	q := &Queue{
+		Rate:     2,                      // one message every 500ms globally
+		Interval: 800 * time.Millisecond, // per recipient
+		now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+		sleepFunc: func(_ context.Context, d time.Duration) error {
+			mu.Lock()
+			defer mu.Unlock()
+			waits = append(waits, d)
+			now = now.Add(d)
+			return nil
+		},
+	}
+
+	noop := func(context.Context) error { return nil }
+	if err := q.Send(context.Background(), "+1", noop, noop); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := q.Send(context.Background(), "+2", noop); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	// +1 first chunk: immediate. Second chunk: recipient interval (800ms)
+	// dominates the global slot (500ms). +2: global slot at +1.3s.
+	want := []time.Duration{800 * time.Millisecond, 500 * time.Millisecond}
+	if len(waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", waits, want)
+	}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("wait[%d] = %v, want %v", i, waits[i], want[i])
+		}
+	}
+}
+
+func TestSendStopsBatchOnError(t *testing.T) {
+	q := &Queue{}
+	r := &recorder{}
+	boom := errors.New("boom")
+
+	err := q.Send(context.Background(), "+1",
+		r.send("a"),
+		func(context.Context) error { return boom },
+		r.send("c"),
+	)
+	if !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want boom", err)
+	}
+	if len(r.sent) != 1 {
+		t.Errorf("sent = %v, want only the first message", r.sent)
+	}
+	if d := q.Depth(); d != 0 {
+		t.Errorf("Depth() = %d, want 0", d)
+	}
+}
+
+func TestSendCancelledWhileQueued(t *testing.T) {
+	q := &Queue{}
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	// Block the lane with a slow send.
+	go func() {
+		_ = q.Send(context.Background(), "+1", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	if d := q.Depth(); d != 1 {
+		t.Errorf("Depth() = %d while one send is in flight, want 1", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &recorder{}
+	errCh := make(chan error, 1)
+	go func() { errCh <- q.Send(ctx, "+1", r.send("late")) }()
+
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	close(release)
+
+	// The cancelled message must be skipped once the lane reaches it.
+	deadline := time.Now().Add(time.Second)
+	for q.Depth() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if d := q.Depth(); d != 0 {
+		t.Errorf("Depth() = %d, want 0", d)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sent) != 0 {
+		t.Errorf("cancelled message was sent: %v", r.sent)
+	}
+}
+
+func TestSendPrunesIdleLanes(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Unix(1700000000, 0)
+	q := &Queue{
+		Interval: time.Second,
+		now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	lanes := func() int {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		n := 0
+		for _, l := range q.lanes {
+			if !l.running {
+				n++
+			}
+		}
+		if n != len(q.lanes) {
+			return -1 // a drain goroutine has not finished yet
+		}
+		return n
+	}
+	settled := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for lanes() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("lanes = %d, want %d", lanes(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	noop := func(context.Context) error { return nil }
+	for _, to := range []string{"+1", "+2", "+3"} {
+		if err := q.Send(context.Background(), to, noop); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	settled(3)
+
+	// Past the interval, the next Send sweeps the idle recipients.
+	advance(2 * time.Second)
+	if err := q.Send(context.Background(), "+4", noop); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	settled(1)
+}
@@ -0,0 +1,149 @@
+// Package status records delivery statuses (sent, delivered, read, failed)
+// of outbound WhatsApp messages, as reported by webhooks. Updates are
+// appended to a JSONL file in the state directory so the CLI can look them
+// up while the bridge is running.
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+)
+
+// DefaultMaxSize is the log size at which it is rotated to Path+".1".
+const DefaultMaxSize = 5 * 1024 * 1024
+
+// Update is one status change of one message.
+type Update struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	Recipient   string    `json:"recipient,omitempty"`
+	Time        time.Time `json:"time"`
+	ErrorCode   int       `json:"error_code,omitempty"`
+	ErrorTitle  string    `json:"error_title,omitempty"`
+	ErrorDetail string    `json:"error_detail,omitempty"`
+}
+
+// Failed reports whether the message could not be delivered.
+func (u Update) Failed() bool {
+	return u.Status == "failed"
+}
+
+// FromWebhook converts a webhook status into an Update. Only the first Meta
+// error is kept; WhatsApp sends at most one.
+func FromWebhook(s kapso.Status) Update {
+	u := Update{
+		ID:        s.ID,
+		Status:    strings.ToLower(s.Status),
+		Recipient: s.RecipientID,
+		Time:      kapso.ParseTimestamp(s.Timestamp),
+	}
+	if len(s.Errors) > 0 {
+		e := s.Errors[0]
+		u.ErrorCode = e.Code
+		u.ErrorTitle = e.Title
+		if u.ErrorTitle == "" {
+			u.ErrorTitle = e.Message
+		}
+		if e.ErrorData != nil {
+			u.ErrorDetail = e.ErrorData.Details
+		}
+	}
+	return u
+}
+
+// Store appends updates to a JSONL log at Path.
+type Store struct {
+	Path    string
+	MaxSize int64 // rotate when the log grows past this; 0 = DefaultMaxSize
+
+	mu sync.Mutex
+}
+
+// Record appends u to the log, rotating the log first when it is full.
+func (s *Store) Record(u Update) error {
+	if u.ID == "" {
+		return fmt.Errorf("status update without message ID")
+	}
+	if u.Time.IsZero() {
+		u.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("marshal status: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxSize := s.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if info, err := os.Stat(s.Path); err == nil && info.Size() >= maxSize {
+		if err := os.Rename(s.Path, s.Path+".1"); err != nil {
+			return fmt.Errorf("rotate status log: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open status log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write status log: %w", err)
+	}
+	return f.Close()
+}
+
+// Lookup returns every recorded update for id, oldest first, reading the
+// rotated log before the current one. It returns no error when the log does
+// not exist yet.
+func Lookup(path, id string) ([]Update, error) {
+	var out []Update
+	for _, p := range []string{path + ".1", path} {
+		updates, err := readLog(p, id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, updates...)
+	}
+	return out, nil
+}
+
+func readLog(path, id string) ([]Update, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open status log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var out []Update
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Bytes()
+		// Cheap pre-filter before decoding every line.
+		if !strings.Contains(string(line), id) {
+			continue
+		}
+		var u Update
+		if err := json.Unmarshal(line, &u); err != nil || u.ID != id {
+			continue
+		}
+		out = append(out, u)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read status log: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,104 @@
+package status
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Enriquefft/openclaw-kapso-whatsapp/internal/kapso"
+)
+
+func TestFromWebhook(t *testing.T) {
+	s := kapso.Status{
+		ID:          "wamid.1",
+		Status:      "failed",
+		Timestamp:   "1700000000",
+		RecipientID: "15551234567",
+		Errors: []kapso.StatusError{{
+			Code:      131047,
+			Title:     "Re-engagement message",
+			ErrorData: &kapso.StatusErrorData{Details: "more than 24 hours have passed"},
+		}},
+	}
+	u := FromWebhook(s)
+	if !u.Failed() || u.ErrorCode != 131047 || u.ErrorTitle != "Re-engagement message" {
+		t.Errorf("unexpected update: %+v", u)
+	}
+	if u.ErrorDetail != "more than 24 hours have passed" {
+		t.Errorf("ErrorDetail = %q", u.ErrorDetail)
+	}
+	if !u.Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Time = %v", u.Time)
+	}
+
+	u = FromWebhook(kapso.Status{ID: "wamid.2", Status: "DELIVERED"})
+	if u.Status != "delivered" || u.Failed() {
+		t.Errorf("unexpected update: %+v", u)
+	}
+}
+
+func TestRecordAndLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statuses.jsonl")
+	s := &Store{Path: path}
+
+	for _, u := range []Update{
+		{ID: "wamid.1", Status: "sent"},
+		{ID: "wamid.2", Status: "sent"},
+		{ID: "wamid.1", Status: "delivered"},
+		{ID: "wamid.1", Status: "read"},
+	} {
+		if err := s.Record(u); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	got, err := Lookup(path, "wamid.1")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	var statuses []string
+	for _, u := range got {
+		statuses = append(statuses, u.Status)
+	}
+	if len(statuses) != 3 || statuses[0] != "sent" || statuses[2] != "read" {
+		t.Errorf("statuses = %v, want [sent delivered read]", statuses)
+	}
+
+	if got, _ := Lookup(path, "wamid.9"); len(got) != 0 {
+		t.Errorf("expected no updates for unknown ID, got %v", got)
+	}
+	if err := s.Record(Update{Status: "sent"}); err == nil {
+		t.Error("expected error for update without ID")
+	}
+}
+
+func TestRecordRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statuses.jsonl")
+	s := &Store{Path: path, MaxSize: 1}
+
+	if err := s.Record(Update{ID: "wamid.1", Status: "sent"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record(Update{ID: "wamid.1", Status: "failed", ErrorCode: 131026}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected rotated log: %v", err)
+	}
+
+	got, err := Lookup(path, "wamid.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Status != "sent" || got[1].ErrorCode != 131026 {
+		t.Errorf("expected both updates across rotation, got %+v", got)
+	}
+}
+
+func TestLookupMissingLog(t *testing.T) {
+	got, err := Lookup(filepath.Join(t.TempDir(), "none.jsonl"), "wamid.1")
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %v, %v; want nothing", got, err)
+	}
+}